@@ -0,0 +1,97 @@
+// Package adjuster turns raw OTel cumulative points read from ClickHouse
+// into Prometheus-friendly series, the way the OTel Collector's Prometheus
+// receiver metrics_adjuster does: it tracks each series' StartTimeUnix to
+// detect process restarts and inject a synthetic reset sample, and marks a
+// series stale once it hasn't been observed for a configurable interval.
+package adjuster
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/lru"
+)
+
+// DefaultStalenessInterval is how long a series may go unseen, as of the
+// query's end time, before Adjuster appends a stale marker for it.
+const DefaultStalenessInterval = 5 * time.Minute
+
+// defaultCacheSize and defaultCacheTTL bound the per-series start-time cache
+// so long-running readers don't grow it unbounded.
+const (
+	defaultCacheSize = 16384
+	defaultCacheTTL  = time.Hour
+)
+
+type seriesState struct {
+	startTime time.Time
+}
+
+// Adjuster tracks per-series start times across queries for MetricTypeSum
+// (and histogram/summary counts), so Reader.Read can hand back samples that
+// behave correctly under rate()/increase() despite counter resets.
+type Adjuster struct {
+	staleness time.Duration
+	cache     *lru.Cache[string, *seriesState]
+}
+
+// New creates an Adjuster with the given staleness interval. A staleness of
+// 0 uses DefaultStalenessInterval.
+func New(staleness time.Duration) *Adjuster {
+	if staleness <= 0 {
+		staleness = DefaultStalenessInterval
+	}
+	return &Adjuster{
+		staleness: staleness,
+		cache:     lru.New[string, *seriesState](defaultCacheSize, defaultCacheTTL),
+	}
+}
+
+// Adjust rewrites samples (expected in ascending timestamp order) for the
+// series identified by fingerprint. If startTime advanced since the last
+// call for this fingerprint, a synthetic zero sample is injected at the new
+// start time ahead of samples, so a PromQL rate()/increase() sees a reset
+// rather than a cliff back to zero. If the last sample is older than the
+// staleness interval as of queryEnd, a stale marker sample is appended.
+func (a *Adjuster) Adjust(fingerprint string, startTime time.Time, samples []prompb.Sample, queryEnd time.Time) []prompb.Sample {
+	if len(samples) == 0 {
+		return samples
+	}
+
+	out := samples
+	if a.Reset(fingerprint, startTime) {
+		reset := prompb.Sample{Timestamp: startTime.UnixMilli(), Value: 0}
+		out = append([]prompb.Sample{reset}, samples...)
+	}
+
+	lastSample := samples[len(samples)-1]
+	if a.Stale(time.UnixMilli(lastSample.Timestamp), queryEnd) {
+		out = append(out, prompb.Sample{Timestamp: queryEnd.UnixMilli(), Value: math.Float64frombits(value.StaleNaN)})
+	}
+
+	return out
+}
+
+// Reset reports whether startTime has advanced since the last Reset call
+// for fingerprint (signalling a counter reset / process restart), and
+// records startTime for next time regardless of the prior value. Exposed
+// separately from Adjust so streaming callers that can't hold a whole
+// series in memory can still detect resets one row at a time.
+func (a *Adjuster) Reset(fingerprint string, startTime time.Time) bool {
+	state, seen := a.cache.Get(fingerprint)
+	reset := seen && startTime.After(state.startTime)
+	if !seen {
+		state = &seriesState{}
+	}
+	state.startTime = startTime
+	a.cache.Set(fingerprint, state)
+	return reset
+}
+
+// Stale reports whether lastSample is older than the configured staleness
+// interval as of queryEnd.
+func (a *Adjuster) Stale(lastSample, queryEnd time.Time) bool {
+	return queryEnd.Sub(lastSample) > a.staleness
+}
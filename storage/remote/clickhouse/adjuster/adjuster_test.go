@@ -0,0 +1,90 @@
+package adjuster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func TestAdjuster_NoResetOnFirstSight(t *testing.T) {
+	a := New(5 * time.Minute)
+	start := time.Unix(1000, 0)
+	queryEnd := start.Add(time.Minute)
+
+	samples := []prompb.Sample{{Timestamp: start.Add(30 * time.Second).UnixMilli(), Value: 5}}
+	got := a.Adjust("fp1", start, samples, queryEnd)
+
+	if len(got) != 1 {
+		t.Fatalf("Adjust() = %v, want 1 sample (no reset on first sight)", got)
+	}
+}
+
+func TestAdjuster_InjectsResetOnRestart(t *testing.T) {
+	a := New(5 * time.Minute)
+	start := time.Unix(1000, 0)
+	firstEnd := start.Add(time.Minute)
+
+	a.Adjust("fp1", start, []prompb.Sample{{Timestamp: firstEnd.UnixMilli(), Value: 5}}, firstEnd)
+
+	newStart := start.Add(10 * time.Minute)
+	secondSamples := []prompb.Sample{{Timestamp: newStart.Add(time.Minute).UnixMilli(), Value: 2}}
+	got := a.Adjust("fp1", newStart, secondSamples, newStart.Add(time.Minute))
+
+	if len(got) != 2 {
+		t.Fatalf("Adjust() = %v, want reset sample + 1 real sample", got)
+	}
+	if got[0].Timestamp != newStart.UnixMilli() || got[0].Value != 0 {
+		t.Errorf("Adjust() reset sample = %+v, want {Timestamp: %d, Value: 0}", got[0], newStart.UnixMilli())
+	}
+}
+
+func TestAdjuster_AppendsStaleMarker(t *testing.T) {
+	a := New(5 * time.Minute)
+	start := time.Unix(1000, 0)
+	lastSample := start.Add(time.Minute)
+	queryEnd := lastSample.Add(10 * time.Minute)
+
+	got := a.Adjust("fp1", start, []prompb.Sample{{Timestamp: lastSample.UnixMilli(), Value: 5}}, queryEnd)
+
+	last := got[len(got)-1]
+	if last.Timestamp != queryEnd.UnixMilli() || !value.IsStaleNaN(last.Value) {
+		t.Errorf("Adjust() last sample = %+v, want stale marker at %d", last, queryEnd.UnixMilli())
+	}
+}
+
+func TestAdjuster_ResetAndStale(t *testing.T) {
+	a := New(time.Minute)
+	start := time.Unix(1000, 0)
+
+	if a.Reset("fp1", start) {
+		t.Error("Reset() on first sight = true, want false")
+	}
+	if a.Reset("fp1", start.Add(time.Second)) == false {
+		t.Error("Reset() on advanced start time = false, want true")
+	}
+	if a.Reset("fp1", start) {
+		t.Error("Reset() on earlier start time = true, want false")
+	}
+
+	if a.Stale(start, start.Add(30*time.Second)) {
+		t.Error("Stale() within interval = true, want false")
+	}
+	if !a.Stale(start, start.Add(2*time.Minute)) {
+		t.Error("Stale() beyond interval = false, want true")
+	}
+}
+
+func TestAdjuster_NoStaleMarkerWithinInterval(t *testing.T) {
+	a := New(5 * time.Minute)
+	start := time.Unix(1000, 0)
+	lastSample := start.Add(time.Minute)
+	queryEnd := lastSample.Add(time.Minute)
+
+	got := a.Adjust("fp1", start, []prompb.Sample{{Timestamp: lastSample.UnixMilli(), Value: 5}}, queryEnd)
+
+	if len(got) != 1 {
+		t.Fatalf("Adjust() = %v, want no stale marker within staleness interval", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Package cache provides a pluggable result cache for the ClickHouse
+// remote-read path, so repeated remote_read requests (the common case under
+// a thundering herd of Prometheus queriers polling the same dashboards)
+// don't each force a round-trip to ClickHouse.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// Cache stores serialized query results keyed by Key. Implementations must
+// be safe for concurrent use.
+type Cache interface {
+	// Get returns the cached value for key, if present and not expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Key hashes a normalized representation of matchers, the query's time
+// range, and its resolved metric type into a cache key, so two equivalent
+// remote_read queries (same matchers in any order, same range, same type)
+// always hash to the same key.
+func Key(matchers []*labels.Matcher, mint, maxt time.Time, metricType model.MetricType) string {
+	normalized := make([]*labels.Matcher, len(matchers))
+	copy(normalized, matchers)
+	sort.Slice(normalized, func(i, j int) bool {
+		if normalized[i].Name != normalized[j].Name {
+			return normalized[i].Name < normalized[j].Name
+		}
+		if normalized[i].Value != normalized[j].Value {
+			return normalized[i].Value < normalized[j].Value
+		}
+		return normalized[i].Type < normalized[j].Type
+	})
+
+	h := sha256.New()
+	for _, m := range normalized {
+		h.Write([]byte(m.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Type.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(m.Value))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(mint.UTC().Format(time.RFC3339Nano)))
+	h.Write([]byte{0})
+	h.Write([]byte(maxt.UTC().Format(time.RFC3339Nano)))
+	h.Write([]byte{0})
+	h.Write([]byte(metricType))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
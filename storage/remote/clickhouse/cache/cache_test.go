@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_OrderIndependent(t *testing.T) {
+	mint := time.Unix(0, 0)
+	maxt := time.Unix(3600, 0)
+
+	a := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		labels.MustNewMatcher(labels.MatchEqual, "job", "api"),
+	}
+	b := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "job", "api"),
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+	}
+
+	assert.Equal(t, Key(a, mint, maxt, model.MetricTypeGauge), Key(b, mint, maxt, model.MetricTypeGauge))
+}
+
+func TestKey_DiffersByMetricType(t *testing.T) {
+	mint := time.Unix(0, 0)
+	maxt := time.Unix(3600, 0)
+	matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", "up")}
+
+	assert.NotEqual(t,
+		Key(matchers, mint, maxt, model.MetricTypeGauge),
+		Key(matchers, mint, maxt, model.MetricTypeSum),
+	)
+}
+
+func TestLRUCache_GetSet(t *testing.T) {
+	c := NewLRUCache(2, time.Hour, NewMetrics(nil))
+	ctx := context.Background()
+
+	_, ok, err := c.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Set(ctx, "k", []byte("v"), time.Hour))
+	value, ok, err := c.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v"), value)
+}
+
+func TestLRUCache_NilMetricsDefaults(t *testing.T) {
+	c := NewLRUCache(2, time.Hour, nil)
+	ctx := context.Background()
+
+	assert.NotPanics(t, func() {
+		_, _, _ = c.Get(ctx, "missing")
+		_ = c.Set(ctx, "k", []byte("v"), time.Hour)
+	})
+}
+
+// fakeRedisClient is a minimal in-memory RedisClient for exercising
+// RedisCache without a real Redis instance.
+type fakeRedisClient struct {
+	values map[string][]byte
+}
+
+func (f *fakeRedisClient) Get(_ context.Context, key string) ([]byte, error) {
+	value, ok := f.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *fakeRedisClient) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	if f.values == nil {
+		f.values = make(map[string][]byte)
+	}
+	f.values[key] = value
+	return nil
+}
+
+func TestRedisCache_NilMetricsDefaults(t *testing.T) {
+	c := NewRedisCache(&fakeRedisClient{}, nil)
+	ctx := context.Background()
+
+	assert.NotPanics(t, func() {
+		_, _, _ = c.Get(ctx, "missing")
+		_ = c.Set(ctx, "k", []byte("v"), time.Hour)
+		_, _, _ = c.Get(ctx, "k")
+	})
+}
+
+func TestLRUCache_EvictsOverCapacity(t *testing.T) {
+	metrics := NewMetrics(nil)
+	c := NewLRUCache(1, time.Hour, metrics)
+	ctx := context.Background()
+
+	assert.NoError(t, c.Set(ctx, "a", []byte("1"), time.Hour))
+	assert.NoError(t, c.Set(ctx, "b", []byte("2"), time.Hour))
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok, "oldest entry should have been evicted")
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/lru"
+)
+
+// LRUCache is an in-memory, size-capped Cache implementation. Entries
+// expire after the ttl given to NewLRUCache; the ttl passed to Set is
+// ignored, since the underlying lru.Cache only supports a single
+// cache-wide TTL (the same trade-off the resolver and adjuster caches make).
+type LRUCache struct {
+	cache   *lru.Cache[string, []byte]
+	size    int
+	metrics *Metrics
+}
+
+// NewLRUCache returns an LRUCache holding at most size entries for up to
+// ttl each, reporting hits/misses/evictions to metrics. metrics may be nil,
+// in which case unregistered defaults are used (mirroring writer.NewWriter's
+// nil-Metrics convention).
+func NewLRUCache(size int, ttl time.Duration, metrics *Metrics) *LRUCache {
+	if metrics == nil {
+		metrics = NewMetrics(nil)
+	}
+	return &LRUCache{
+		cache:   lru.New[string, []byte](size, ttl),
+		size:    size,
+		metrics: metrics,
+	}
+}
+
+func (c *LRUCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	value, ok := c.cache.Get(key)
+	if ok {
+		c.metrics.Hits.Inc()
+	} else {
+		c.metrics.Misses.Inc()
+	}
+	return value, ok, nil
+}
+
+func (c *LRUCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	_, existed := c.cache.Get(key)
+	if !existed && c.cache.Len() >= c.size {
+		c.metrics.Evictions.Inc()
+	}
+	c.cache.Set(key, value)
+	return nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds the counters exposed for the query cache: hits, misses,
+// evictions, and queries that were coalesced onto an in-flight ClickHouse
+// call via singleflight instead of starting their own.
+type Metrics struct {
+	Hits               prometheus.Counter
+	Misses             prometheus.Counter
+	Evictions          prometheus.Counter
+	SingleflightShares prometheus.Counter
+}
+
+// NewMetrics registers and returns the query cache's metrics with reg. reg
+// may be nil, in which case the metrics are created but left unregistered
+// (useful in tests).
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		Hits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "query_cache",
+			Name:      "hits_total",
+			Help:      "Number of remote-read queries served from the query cache.",
+		}),
+		Misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "query_cache",
+			Name:      "misses_total",
+			Help:      "Number of remote-read queries not found in the query cache.",
+		}),
+		Evictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "query_cache",
+			Name:      "evictions_total",
+			Help:      "Number of query cache entries evicted to make room for new ones.",
+		}),
+		SingleflightShares: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "query_cache",
+			Name:      "singleflight_shares_total",
+			Help:      "Number of remote-read queries that shared an in-flight ClickHouse call instead of starting their own.",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(m.Hits, m.Misses, m.Evictions, m.SingleflightShares)
+	}
+
+	return m
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by a RedisClient's Get when key isn't present, so
+// RedisCache can distinguish a miss from a connection error.
+var ErrNotFound = errors.New("cache: key not found")
+
+// RedisClient is the subset of a Redis client's API RedisCache needs,
+// satisfied by e.g. *redis.Client from github.com/redis/go-redis/v9. It
+// exists so this package doesn't need to depend on a specific Redis driver.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// RedisCache is a Cache implementation backed by a shared Redis instance,
+// for deployments that want cached query results to survive a reader
+// restart or be shared across replicas.
+type RedisCache struct {
+	client  RedisClient
+	metrics *Metrics
+}
+
+// NewRedisCache returns a RedisCache using client for storage, reporting
+// hits/misses to metrics. Evictions aren't reported, since Redis manages
+// its own memory policy rather than delegating eviction decisions to us.
+func NewRedisCache(client RedisClient, metrics *Metrics) *RedisCache {
+	if metrics == nil {
+		metrics = NewMetrics(nil)
+	}
+	return &RedisCache{client: client, metrics: metrics}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		c.metrics.Misses.Inc()
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	c.metrics.Hits.Inc()
+	return value, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl)
+}
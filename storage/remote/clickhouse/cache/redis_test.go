@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCache_GetSet(t *testing.T) {
+	client := &fakeRedisClient{values: map[string][]byte{}}
+	c := NewRedisCache(client, NewMetrics(nil))
+	ctx := context.Background()
+
+	_, ok, err := c.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Set(ctx, "k", []byte("v"), time.Hour))
+	value, ok, err := c.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v"), value)
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type Client struct {
-	conn driver.Conn
-	opts *Options
+	conn  driver.Conn
+	opts  *Options
+	instr *instrumentation
 }
 
 // NewClient creates a new ClickHouse client
@@ -20,6 +21,11 @@ func NewClient(opts *Options) (*Client, error) {
 		opts = DefaultOptions()
 	}
 
+	instr, err := newInstrumentation(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up clickhouse client instrumentation: %w", err)
+	}
+
 	// Build ClickHouse connection config
 	config := &clickhouse.Options{
 		Addr: opts.Addresses,
@@ -50,28 +56,100 @@ func NewClient(opts *Options) (*Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), opts.DialTimeout)
 	defer cancel()
 
-	if err := conn.Ping(ctx); err != nil {
+	ctx, span := instr.startSpan(ctx, opts, "ping", "", "")
+	start := time.Now()
+	err = conn.Ping(ctx)
+	instr.finish(ctx, span, opts, "ping", time.Since(start), err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to ping clickhouse: %w", err)
 	}
 
 	return &Client{
-		conn: conn,
-		opts: opts,
+		conn:  conn,
+		opts:  opts,
+		instr: instr,
 	}, nil
 }
 
-// Query executes a query and returns rows
-func (c *Client) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+// NewClientFromConn wraps an already-established driver.Conn in a Client,
+// bypassing NewClient's dial/ping. This is mainly useful in tests that need
+// to inject a mock driver.Conn from another package.
+func NewClientFromConn(conn driver.Conn, opts *Options) *Client {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	instr, err := newInstrumentation(opts)
+	if err != nil {
+		// newInstrumentation only fails if a MeterProvider returns bad
+		// instruments, which the global/no-op providers never do.
+		panic(fmt.Sprintf("clickhouse client: setting up instrumentation: %v", err))
+	}
+	return &Client{conn: conn, opts: opts, instr: instr}
+}
+
+// query is Query's implementation, taking an extra metricType so
+// MetricsClient's per-type query methods can record it as the
+// prometheus.metric_type span/metric attribute.
+func (c *Client) query(ctx context.Context, query, metricType string, args ...interface{}) (Rows, error) {
+	ctx, span := c.instr.startSpan(ctx, c.opts, "query", query, metricType)
+	start := time.Now()
 	rows, err := c.conn.Query(ctx, query, args...)
+	c.instr.finish(ctx, span, c.opts, "query", time.Since(start), err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
-	return &clickhouseRows{rows: rows}, nil
+	return &tracedRows{
+		Rows:  &clickhouseRows{rows: rows},
+		ctx:   ctx,
+		instr: c.instr,
+		op:    "query",
+	}, nil
+}
+
+// Query executes a query and returns rows
+func (c *Client) Query(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return c.query(ctx, query, "", args...)
 }
 
 // Close closes the client connection
 func (c *Client) Close() error {
-	return c.conn.Close()
+	ctx, span := c.instr.startSpan(context.Background(), c.opts, "close", "", "")
+	start := time.Now()
+	err := c.conn.Close()
+	c.instr.finish(ctx, span, c.opts, "close", time.Since(start), err)
+	return err
+}
+
+// Batch represents a column-oriented batch insert, as used by the write
+// path to bulk-append rows before flushing them in one round-trip.
+type Batch interface {
+	// Append adds a row to the batch, one value per column in the order
+	// given to PrepareBatch.
+	Append(args ...interface{}) error
+	// Send flushes the batch to ClickHouse.
+	Send() error
+}
+
+type clickhouseBatch struct {
+	batch driver.Batch
+}
+
+func (b *clickhouseBatch) Append(args ...interface{}) error {
+	return b.batch.Append(args...)
+}
+
+func (b *clickhouseBatch) Send() error {
+	return b.batch.Send()
+}
+
+// PrepareBatch prepares a column-oriented batch insert against query, using
+// clickhouse-go's native batch protocol for efficient bulk appends.
+func (c *Client) PrepareBatch(ctx context.Context, query string) (Batch, error) {
+	batch, err := c.conn.PrepareBatch(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	return &clickhouseBatch{batch: batch}, nil
 }
 
 // Rows implementation
@@ -122,20 +200,25 @@ func NewMetricsClient(opts *Options) (*MetricsClient, error) {
 
 // QueryGauge queries gauge metrics
 func (c *MetricsClient) QueryGauge(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	return c.Query(ctx, query, args...)
+	return c.query(ctx, query, "gauge", args...)
 }
 
 // QueryHistogram queries histogram metrics
 func (c *MetricsClient) QueryHistogram(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	return c.Query(ctx, query, args...)
+	return c.query(ctx, query, "histogram", args...)
 }
 
 // QuerySum queries sum metrics
 func (c *MetricsClient) QuerySum(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	return c.Query(ctx, query, args...)
+	return c.query(ctx, query, "sum", args...)
 }
 
 // QuerySummary queries summary metrics
 func (c *MetricsClient) QuerySummary(ctx context.Context, query string, args ...interface{}) (Rows, error) {
-	return c.Query(ctx, query, args...)
+	return c.query(ctx, query, "summary", args...)
+}
+
+// QueryNativeHistogram queries native histogram metrics
+func (c *MetricsClient) QueryNativeHistogram(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return c.query(ctx, query, "native_histogram", args...)
 }
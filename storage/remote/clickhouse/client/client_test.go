@@ -76,13 +76,13 @@ func TestNewClient(t *testing.T) {
 func TestClient_Query(t *testing.T) {
 	mockConn := new(MockConn)
 	mockRows := new(MockRows)
-	client := &Client{conn: mockConn}
+	client := NewClientFromConn(mockConn, nil)
 
 	ctx := context.Background()
 	query := "SELECT * FROM test"
 	args := []interface{}{}
 
-	mockConn.On("Query", ctx, query, args).Return(mockRows, nil)
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
 	mockRows.On("Next").Return(false)
 	mockRows.On("Close").Return(nil)
 
@@ -98,7 +98,7 @@ func TestClient_Query(t *testing.T) {
 
 func TestClient_Close(t *testing.T) {
 	mockConn := new(MockConn)
-	client := &Client{conn: mockConn}
+	client := NewClientFromConn(mockConn, nil)
 
 	mockConn.On("Close").Return(nil)
 
@@ -111,13 +111,13 @@ func TestClient_Close(t *testing.T) {
 func TestMetricsClient_QueryGauge(t *testing.T) {
 	mockConn := new(MockConn)
 	mockRows := new(MockRows)
-	client := &MetricsClient{Client: &Client{conn: mockConn}}
+	client := &MetricsClient{Client: NewClientFromConn(mockConn, nil)}
 
 	ctx := context.Background()
 	query := "SELECT * FROM gauge"
 	args := []interface{}{}
 
-	mockConn.On("Query", ctx, query, args).Return(mockRows, nil)
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
 	mockRows.On("Next").Return(false)
 	mockRows.On("Close").Return(nil)
 
@@ -134,13 +134,13 @@ func TestMetricsClient_QueryGauge(t *testing.T) {
 func TestMetricsClient_QueryHistogram(t *testing.T) {
 	mockConn := new(MockConn)
 	mockRows := new(MockRows)
-	client := &MetricsClient{Client: &Client{conn: mockConn}}
+	client := &MetricsClient{Client: NewClientFromConn(mockConn, nil)}
 
 	ctx := context.Background()
 	query := "SELECT * FROM histogram"
 	args := []interface{}{}
 
-	mockConn.On("Query", ctx, query, args).Return(mockRows, nil)
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
 	mockRows.On("Next").Return(false)
 	mockRows.On("Close").Return(nil)
 
@@ -157,13 +157,13 @@ func TestMetricsClient_QueryHistogram(t *testing.T) {
 func TestMetricsClient_QuerySum(t *testing.T) {
 	mockConn := new(MockConn)
 	mockRows := new(MockRows)
-	client := &MetricsClient{Client: &Client{conn: mockConn}}
+	client := &MetricsClient{Client: NewClientFromConn(mockConn, nil)}
 
 	ctx := context.Background()
 	query := "SELECT * FROM sum"
 	args := []interface{}{}
 
-	mockConn.On("Query", ctx, query, args).Return(mockRows, nil)
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
 	mockRows.On("Next").Return(false)
 	mockRows.On("Close").Return(nil)
 
@@ -180,13 +180,13 @@ func TestMetricsClient_QuerySum(t *testing.T) {
 func TestMetricsClient_QuerySummary(t *testing.T) {
 	mockConn := new(MockConn)
 	mockRows := new(MockRows)
-	client := &MetricsClient{Client: &Client{conn: mockConn}}
+	client := &MetricsClient{Client: NewClientFromConn(mockConn, nil)}
 
 	ctx := context.Background()
 	query := "SELECT * FROM summary"
 	args := []interface{}{}
 
-	mockConn.On("Query", ctx, query, args).Return(mockRows, nil)
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
 	mockRows.On("Next").Return(false)
 	mockRows.On("Close").Return(nil)
 
@@ -200,6 +200,29 @@ func TestMetricsClient_QuerySummary(t *testing.T) {
 	mockRows.AssertExpectations(t)
 }
 
+func TestMetricsClient_QueryNativeHistogram(t *testing.T) {
+	mockConn := new(MockConn)
+	mockRows := new(MockRows)
+	client := &MetricsClient{Client: NewClientFromConn(mockConn, nil)}
+
+	ctx := context.Background()
+	query := "SELECT * FROM exponential_histogram"
+	args := []interface{}{}
+
+	mockConn.On("Query", mock.Anything, query, args).Return(mockRows, nil)
+	mockRows.On("Next").Return(false)
+	mockRows.On("Close").Return(nil)
+
+	rows, err := client.QueryNativeHistogram(ctx, query, args...)
+	assert.NoError(t, err)
+	assert.NotNil(t, rows)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Close())
+
+	mockConn.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
+
 func TestClickhouseRows_Next(t *testing.T) {
 	mockRows := new(MockRows)
 	mockRows.On("Next").Return(true)
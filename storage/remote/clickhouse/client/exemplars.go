@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/fingerprint"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/query"
+)
+
+// ExemplarsClient queries the otel_metrics_exemplars table for the
+// /api/v1/query_exemplars remote-read variant.
+type ExemplarsClient struct {
+	*Client
+	builder *query.Builder
+}
+
+// NewExemplarsClient creates an ExemplarsClient connected per opts.
+func NewExemplarsClient(opts *Options) (*ExemplarsClient, error) {
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ExemplarsClient{Client: c, builder: query.NewBuilder()}, nil
+}
+
+// NewExemplarsClientFromConn wraps an already-established driver.Conn in an
+// ExemplarsClient, bypassing NewExemplarsClient's dial/ping, the same way
+// NewClientFromConn does for Client. This is mainly useful in tests that
+// need to inject a mock driver.Conn from another package.
+func NewExemplarsClientFromConn(conn driver.Conn, opts *Options) *ExemplarsClient {
+	return &ExemplarsClient{Client: NewClientFromConn(conn, opts), builder: query.NewBuilder()}
+}
+
+// QueryExemplars returns the exemplars attached to series matching matchers
+// within [start, end]. A sample's exemplar can be written alongside more
+// than one otel_metrics_* row it's attributed to, so results are
+// deduplicated by (series, timestamp); any exemplar whose label set exceeds
+// Prometheus's 128-byte limit is dropped rather than truncated, since a
+// truncated exemplar could misrepresent the trace it points at.
+func (c *ExemplarsClient) QueryExemplars(ctx context.Context, matchers []*labels.Matcher, start, end time.Time) ([]prompb.Exemplar, error) {
+	sql, args, err := c.builder.BuildExemplarQuery(ctx, start, end, matchers)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.query(ctx, sql, "exemplar", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var result []prompb.Exemplar
+	for rows.Next() {
+		var timestamp int64
+		var value float64
+		var metricName, serviceName string
+		var attributes, resourceAttributes, filteredAttributes map[string]string
+
+		if err := rows.Scan(&timestamp, &value, &metricName, &serviceName, &attributes, &resourceAttributes, &filteredAttributes); err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("%s|%s|%s|%s|%d", metricName, serviceName, fingerprint.Of(attributes), fingerprint.Of(resourceAttributes), timestamp)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		lbls := attributesToLabels(filteredAttributes)
+		if labelSetSize(lbls) > exemplar.ExemplarMaxLabelSetLength {
+			continue
+		}
+
+		result = append(result, prompb.Exemplar{
+			Labels:    lbls,
+			Value:     value,
+			Timestamp: timestamp,
+		})
+	}
+
+	return result, nil
+}
+
+// attributesToLabels converts a FilteredAttributes map into prompb.Labels,
+// sorted for deterministic output.
+func attributesToLabels(attrs map[string]string) []prompb.Label {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := make([]prompb.Label, 0, len(attrs))
+	for _, name := range names {
+		labels = append(labels, prompb.Label{Name: name, Value: attrs[name]})
+	}
+	return labels
+}
+
+// labelSetSize sums the byte length of each label's name and value, matching
+// how Prometheus enforces exemplar.ExemplarMaxLabelSetLength.
+func labelSetSize(lbls []prompb.Label) int {
+	size := 0
+	for _, l := range lbls {
+		size += len(l.Name) + len(l.Value)
+	}
+	return size
+}
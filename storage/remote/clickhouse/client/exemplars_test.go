@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExemplarsClient_QueryExemplars(t *testing.T) {
+	mockConn := new(MockConn)
+	mockRows := new(MockRows)
+	c := NewExemplarsClientFromConn(mockConn, nil)
+
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	end := time.Now()
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "request_latency_seconds"),
+	}
+
+	wantSQL, wantArgs, err := c.builder.BuildExemplarQuery(ctx, start, end, matchers)
+	assert.NoError(t, err)
+
+	mockConn.On("Query", mock.Anything, wantSQL, wantArgs).Return(mockRows, nil)
+	mockRows.On("Next").Return(false)
+	mockRows.On("Close").Return(nil)
+
+	exemplars, err := c.QueryExemplars(ctx, matchers, start, end)
+	assert.NoError(t, err)
+	assert.Empty(t, exemplars)
+
+	mockConn.AssertExpectations(t)
+	mockRows.AssertExpectations(t)
+}
@@ -2,6 +2,9 @@ package client
 
 import (
 	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Options struct {
@@ -24,6 +27,12 @@ type Options struct {
 	CertPath   string
 	KeyPath    string
 	CAPath     string
+
+	// TracerProvider and MeterProvider configure the spans and metrics
+	// recorded for every ClickHouse operation. Nil means use the global
+	// providers (otel.GetTracerProvider/otel.GetMeterProvider).
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
 }
 
 func DefaultOptions() *Options {
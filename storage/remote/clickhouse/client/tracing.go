@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this package as the source of the spans
+// and metrics below, following the OTel convention of naming
+// instrumentation after the Go import path that produces it.
+const instrumentationName = "github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+
+// instrumentation wraps every ClickHouse operation in a span and records
+// per-operation call count, latency, error count, and rows-returned
+// metrics, so operators running the ClickHouse remote-read/write path get
+// per-query traces alongside the rest of Prometheus's tracing.
+type instrumentation struct {
+	tracer trace.Tracer
+
+	calls    metric.Int64Counter
+	errors   metric.Int64Counter
+	duration metric.Float64Histogram
+	rows     metric.Int64Counter
+}
+
+func newInstrumentation(opts *Options) (*instrumentation, error) {
+	tracerProvider := opts.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	meterProvider := opts.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+	meter := meterProvider.Meter(instrumentationName)
+
+	calls, err := meter.Int64Counter(
+		"clickhouse.client.calls",
+		metric.WithDescription("Number of ClickHouse operations performed."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := meter.Int64Counter(
+		"clickhouse.client.errors",
+		metric.WithDescription("Number of ClickHouse operations that returned an error."),
+	)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram(
+		"clickhouse.client.duration",
+		metric.WithDescription("Duration of ClickHouse operations, in seconds."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := meter.Int64Counter(
+		"clickhouse.client.rows",
+		metric.WithDescription("Number of rows returned by ClickHouse queries."),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentation{
+		tracer:   tracerProvider.Tracer(instrumentationName),
+		calls:    calls,
+		errors:   errs,
+		duration: duration,
+		rows:     rows,
+	}, nil
+}
+
+// startSpan starts a span for a single ClickHouse operation (query, ping,
+// close, ...), tagged with the standard db.* attributes plus
+// prometheus.metric_type when metricType is non-empty. The caller is
+// responsible for calling finish with the outcome.
+func (i *instrumentation) startSpan(ctx context.Context, opts *Options, op, statement, metricType string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "clickhouse"),
+		attribute.String("db.name", opts.Database),
+		attribute.String("net.peer.name", peerName(opts)),
+	}
+	if statement != "" {
+		attrs = append(attrs, attribute.String("db.statement", statement))
+	}
+	if metricType != "" {
+		attrs = append(attrs, attribute.String("prometheus.metric_type", metricType))
+	}
+	return i.tracer.Start(ctx, "clickhouse."+op, trace.WithAttributes(attrs...))
+}
+
+// finish records the outcome of the operation started by startSpan: the
+// call/error/duration metrics, a slow-query span event if elapsed exceeds
+// opts.MaxExecutionTime, and the span's error status.
+func (i *instrumentation) finish(ctx context.Context, span trace.Span, opts *Options, op string, elapsed time.Duration, err error) {
+	defer span.End()
+
+	attrs := metric.WithAttributes(attribute.String("operation", op))
+	i.calls.Add(ctx, 1, attrs)
+	i.duration.Record(ctx, elapsed.Seconds(), attrs)
+
+	if opts.MaxExecutionTime > 0 && elapsed > opts.MaxExecutionTime {
+		span.AddEvent("slow query", trace.WithAttributes(
+			attribute.Float64("clickhouse.duration_seconds", elapsed.Seconds()),
+			attribute.Float64("clickhouse.max_execution_time_seconds", opts.MaxExecutionTime.Seconds()),
+		))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		i.errors.Add(ctx, 1, attrs)
+	}
+}
+
+// recordRows records n additional rows returned by a query, attributed to
+// op, once the caller has finished consuming them.
+func (i *instrumentation) recordRows(ctx context.Context, op string, n int64) {
+	if n == 0 {
+		return
+	}
+	i.rows.Add(ctx, n, metric.WithAttributes(attribute.String("operation", op)))
+}
+
+func peerName(opts *Options) string {
+	if len(opts.Addresses) == 0 {
+		return ""
+	}
+	return opts.Addresses[0]
+}
+
+// tracedRows wraps Rows to count how many rows a consumer actually reads,
+// reporting the total to instr once the caller closes the result set.
+type tracedRows struct {
+	Rows
+	ctx   context.Context
+	instr *instrumentation
+	op    string
+	count int64
+}
+
+func (r *tracedRows) Next() bool {
+	ok := r.Rows.Next()
+	if ok {
+		r.count++
+	}
+	return ok
+}
+
+func (r *tracedRows) Close() error {
+	r.instr.recordRows(r.ctx, r.op, r.count)
+	return r.Rows.Close()
+}
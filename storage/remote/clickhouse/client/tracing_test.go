@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerName(t *testing.T) {
+	assert.Equal(t, "", peerName(&Options{}))
+	assert.Equal(t, "ch-1:9000", peerName(&Options{Addresses: []string{"ch-1:9000", "ch-2:9000"}}))
+}
+
+func TestTracedRows_CountsRowsRead(t *testing.T) {
+	mockRows := new(MockRows)
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(true).Once()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Close").Return(nil)
+
+	instr, err := newInstrumentation(DefaultOptions())
+	assert.NoError(t, err)
+
+	rows := &tracedRows{Rows: &clickhouseRows{rows: mockRows}, ctx: context.Background(), instr: instr, op: "query"}
+	for rows.Next() {
+	}
+	assert.Equal(t, int64(2), rows.count)
+	assert.NoError(t, rows.Close())
+
+	mockRows.AssertExpectations(t)
+}
@@ -0,0 +1,30 @@
+// Package fingerprint provides a canonical string encoding of a
+// string-to-string map, shared by the reader, writer, and client packages
+// so they agree on how an attributes/resource-attributes map folds into a
+// series or cache key, regardless of the order ClickHouse returns a map's
+// entries in.
+package fingerprint
+
+import (
+	"sort"
+	"strings"
+)
+
+// Of returns a canonical string representation of m, suitable for use as
+// (part of) a map or cache key, independent of map iteration order.
+func Of(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(m[k])
+		b.WriteByte(',')
+	}
+	return b.String()
+}
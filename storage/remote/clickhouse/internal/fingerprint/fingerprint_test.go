@@ -0,0 +1,19 @@
+package fingerprint
+
+import "testing"
+
+func TestOf_OrderIndependent(t *testing.T) {
+	a := Of(map[string]string{"env": "prod", "job": "api"})
+	b := Of(map[string]string{"job": "api", "env": "prod"})
+	if a != b {
+		t.Errorf("Of() = %q and %q for the same map in different orders, want equal", a, b)
+	}
+}
+
+func TestOf_DiffersByContent(t *testing.T) {
+	a := Of(map[string]string{"env": "prod"})
+	b := Of(map[string]string{"env": "staging"})
+	if a == b {
+		t.Errorf("Of() = %q for both maps, want different fingerprints", a)
+	}
+}
@@ -0,0 +1,117 @@
+// Package histogram converts between Prometheus's span/delta-encoded
+// native histogram buckets (prompb.Histogram) and the dense, offset+counts
+// columnar form in which the otel_metrics_exponential_histogram table
+// stores one side (positive or negative) of a histogram's buckets,
+// mirroring how the OTel Collector's ClickHouse exporter lays out
+// exponential histogram buckets. It is shared by the writer (spans ->
+// dense) and reader (dense -> spans) so the two stay in lockstep.
+package histogram
+
+import "github.com/prometheus/prometheus/prompb"
+
+// Buckets is the dense, gap-filled form of one side of a native
+// histogram's buckets: Offset is the index of the first bucket in Counts,
+// and Counts holds an absolute (not delta-encoded) count for every
+// consecutive bucket from Offset onward, with any gap between spans
+// filled in as zero.
+type Buckets struct {
+	Offset int32
+	Counts []float64
+}
+
+// ExpandDeltas converts an integer histogram's delta-encoded spans into
+// dense, absolute-count Buckets.
+func ExpandDeltas(spans []prompb.BucketSpan, deltas []int64) Buckets {
+	if len(spans) == 0 {
+		return Buckets{}
+	}
+	counts := make([]float64, 0, len(deltas))
+	var running int64
+	var di int
+	for i, span := range spans {
+		if i > 0 {
+			for g := int32(0); g < span.Offset; g++ {
+				counts = append(counts, 0)
+			}
+		}
+		for j := uint32(0); j < span.Length; j++ {
+			running += deltas[di]
+			di++
+			counts = append(counts, float64(running))
+		}
+	}
+	return Buckets{Offset: spans[0].Offset, Counts: counts}
+}
+
+// ExpandCounts is like ExpandDeltas but for a float histogram's spans,
+// whose bucket values are already absolute counts rather than deltas.
+func ExpandCounts(spans []prompb.BucketSpan, bucketCounts []float64) Buckets {
+	if len(spans) == 0 {
+		return Buckets{}
+	}
+	counts := make([]float64, 0, len(bucketCounts))
+	var ci int
+	for i, span := range spans {
+		if i > 0 {
+			for g := int32(0); g < span.Offset; g++ {
+				counts = append(counts, 0)
+			}
+		}
+		for j := uint32(0); j < span.Length; j++ {
+			counts = append(counts, bucketCounts[ci])
+			ci++
+		}
+	}
+	return Buckets{Offset: spans[0].Offset, Counts: counts}
+}
+
+// Collapse re-encodes dense Buckets into delta-encoded spans, the inverse
+// of ExpandDeltas: maximal runs of non-zero buckets become spans, and any
+// zero-count run between them becomes the gap in the next span's Offset.
+func Collapse(b Buckets) ([]prompb.BucketSpan, []int64) {
+	spans, absolute := collapseRuns(b)
+	deltas := make([]int64, len(absolute))
+	var prev int64
+	for i, c := range absolute {
+		cur := int64(c)
+		deltas[i] = cur - prev
+		prev = cur
+	}
+	return spans, deltas
+}
+
+// CollapseCounts is like Collapse but emits absolute per-bucket counts
+// instead of deltas, the inverse of ExpandCounts.
+func CollapseCounts(b Buckets) ([]prompb.BucketSpan, []float64) {
+	return collapseRuns(b)
+}
+
+func collapseRuns(b Buckets) ([]prompb.BucketSpan, []float64) {
+	var spans []prompb.BucketSpan
+	var values []float64
+	prevEnd := int32(0)
+	first := true
+
+	for i := 0; i < len(b.Counts); {
+		if b.Counts[i] == 0 {
+			i++
+			continue
+		}
+		start := i
+		for i < len(b.Counts) && b.Counts[i] != 0 {
+			i++
+		}
+		absStart := b.Offset + int32(start)
+
+		offset := absStart - prevEnd
+		if first {
+			offset = absStart
+			first = false
+		}
+		length := uint32(i - start)
+		spans = append(spans, prompb.BucketSpan{Offset: offset, Length: length})
+		values = append(values, b.Counts[start:i]...)
+		prevEnd = absStart + int32(length)
+	}
+	return spans, values
+}
@@ -0,0 +1,62 @@
+package histogram
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func TestExpandDeltas_FillsGapsBetweenSpans(t *testing.T) {
+	spans := []prompb.BucketSpan{
+		{Offset: -2, Length: 2}, // buckets -2,-1: counts 1,3
+		{Offset: 3, Length: 1},  // gap of 3 zero buckets, then bucket 3: count 5
+	}
+	deltas := []int64{1, 2, 2} // running: 1, 3, 5
+
+	got := ExpandDeltas(spans, deltas)
+	want := Buckets{Offset: -2, Counts: []float64{1, 3, 0, 0, 0, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandDeltas() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollapse_RoundTripsExpandDeltas(t *testing.T) {
+	spans := []prompb.BucketSpan{
+		{Offset: -2, Length: 2},
+		{Offset: 3, Length: 1},
+	}
+	deltas := []int64{1, 2, 2}
+
+	dense := ExpandDeltas(spans, deltas)
+	gotSpans, gotDeltas := Collapse(dense)
+
+	if !reflect.DeepEqual(gotSpans, spans) {
+		t.Errorf("Collapse() spans = %+v, want %+v", gotSpans, spans)
+	}
+	if !reflect.DeepEqual(gotDeltas, deltas) {
+		t.Errorf("Collapse() deltas = %+v, want %+v", gotDeltas, deltas)
+	}
+}
+
+func TestExpandCounts_AndCollapseCounts_RoundTrip(t *testing.T) {
+	spans := []prompb.BucketSpan{
+		{Offset: 0, Length: 2},
+		{Offset: 1, Length: 2},
+	}
+	counts := []float64{1.5, 2.5, 4, 0.5}
+
+	dense := ExpandCounts(spans, counts)
+	wantDense := Buckets{Offset: 0, Counts: []float64{1.5, 2.5, 0, 4, 0.5}}
+	if !reflect.DeepEqual(dense, wantDense) {
+		t.Errorf("ExpandCounts() = %+v, want %+v", dense, wantDense)
+	}
+
+	gotSpans, gotCounts := CollapseCounts(dense)
+	if !reflect.DeepEqual(gotSpans, spans) {
+		t.Errorf("CollapseCounts() spans = %+v, want %+v", gotSpans, spans)
+	}
+	if !reflect.DeepEqual(gotCounts, counts) {
+		t.Errorf("CollapseCounts() counts = %+v, want %+v", gotCounts, counts)
+	}
+}
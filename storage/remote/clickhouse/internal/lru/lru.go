@@ -0,0 +1,111 @@
+// Package lru provides a minimal size- and TTL-bounded LRU cache shared by
+// the ClickHouse remote-read/write components (metric-type resolution,
+// counter-reset tracking, query results) so none of them need to reach for
+// an external caching dependency for what is, in each case, a small bounded
+// map.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Cache is a fixed-size LRU cache, optionally bounding entry lifetime by TTL.
+// It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[K]*list.Element
+	now   func() time.Time
+}
+
+// New returns a Cache holding at most size entries. A ttl of 0 disables
+// expiry and entries are evicted purely by recency once size is exceeded.
+func New[K comparable, V any](size int, ttl time.Duration) *Cache[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	return &Cache[K, V]{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[K]*list.Element, size),
+		now:   time.Now,
+	}
+}
+
+// Get returns the cached value for key, if present and not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	e := el.Value.(*entry[K, V])
+	if c.ttl > 0 && c.now().After(e.expiresAt) {
+		c.removeElement(el)
+		var zero V
+		return zero, false
+	}
+
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Set inserts or updates the value for key, evicting the least recently
+// used entry if the cache is over capacity.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = c.now().Add(c.ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	c.items[key] = el
+	if c.ll.Len() > c.size {
+		c.removeOldest()
+	}
+}
+
+// Len reports the number of entries currently cached.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache[K, V]) removeOldest() {
+	if el := c.ll.Back(); el != nil {
+		c.removeElement(el)
+	}
+}
+
+func (c *Cache[K, V]) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	e := el.Value.(*entry[K, V])
+	delete(c.items, e.key)
+}
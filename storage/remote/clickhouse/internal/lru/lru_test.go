@@ -0,0 +1,45 @@
+package lru
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_GetSet(t *testing.T) {
+	c := New[string, int](2, 0)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get() on empty cache returned ok=true")
+	}
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %v, %v, want 1, true", v, ok)
+	}
+
+	// Evicts "b" (least recently used after the Get("a") above).
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Get(b) expected eviction, got ok=true")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) = %v, %v, want 3, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestCache_TTLExpiry(t *testing.T) {
+	c := New[string, int](10, time.Millisecond)
+	c.Set("a", 1)
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get(a) expected expiry, got ok=true")
+	}
+}
@@ -15,28 +15,35 @@ const (
 	MetricTypeSum       MetricType = "sum"
 	MetricTypeHistogram MetricType = "histogram"
 	MetricTypeSummary   MetricType = "summary"
+
+	// MetricTypeNativeHistogram identifies a Prometheus native (sparse,
+	// exponential-bucket) histogram, stored in
+	// otel_metrics_exponential_histogram rather than otel_metrics_histogram.
+	MetricTypeNativeHistogram MetricType = "native_histogram"
 )
 
 // QueryBuilder defines interface for building ClickHouse queries
 type QueryBuilder interface {
-	// BuildQuery constructs a ClickHouse SQL query from Prometheus query params
-	BuildQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType MetricType) (string, error)
-}
+	// BuildQuery constructs a parameterized ClickHouse SQL query from
+	// Prometheus query params. args must be passed to Client.Query alongside
+	// the returned SQL; matcher values are never string-concatenated into it.
+	BuildQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType MetricType) (sql string, args []interface{}, err error)
 
-// Client defines interface for ClickHouse operations
-type Client interface {
-	// Query executes a query and returns rows
-	Query(ctx context.Context, query string) (Rows, error)
-	// Close closes the client connection
-	Close() error
+	// BuildStreamQuery is like BuildQuery but orders rows by series
+	// (MetricName, ServiceName, Attributes) ahead of TimeUnix, so a
+	// streaming reader can emit each series' chunk as soon as it sees the
+	// next series, without buffering rows to re-sort them.
+	BuildStreamQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType MetricType) (sql string, args []interface{}, err error)
 }
 
-// Rows represents a result set from ClickHouse
-type Rows interface {
-	// Next advances the cursor to next row
-	Next() bool
-	// Scan copies the current row into the provided destination
-	Scan(dest ...interface{}) error
-	// Close closes the rows iterator
-	Close() error
+// MetricTypeResolver determines which OTel metric type (and therefore which
+// otel_metrics_* table) the series selected by matchers live in. Reader.Read
+// consults a resolver before calling QueryBuilder.BuildQuery so the table
+// choice is based on what ClickHouse actually holds for the metric rather
+// than guessed from the metric name or surrounding PromQL.
+type MetricTypeResolver interface {
+	// ResolveMetricType returns the MetricType for the series identified by
+	// matchers, or an error if the resolver cannot determine one (allowing a
+	// caller to fall back to another resolver).
+	ResolveMetricType(ctx context.Context, matchers []*labels.Matcher) (MetricType, error)
 }
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -10,6 +11,11 @@ import (
 	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
 )
 
+// identifierRe allow-lists the characters permitted in a dynamic ClickHouse
+// map key (an attribute/label name). Map keys can't be bound as query
+// parameters, so names are validated against this instead of escaped.
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
 type Builder struct {
 	// tableMapping maps metric types to their corresponding tables
 	tableMapping map[model.MetricType]string
@@ -18,35 +24,55 @@ type Builder struct {
 func NewBuilder() *Builder {
 	return &Builder{
 		tableMapping: map[model.MetricType]string{
-			model.MetricTypeGauge:     "otel_metrics_gauge",
-			model.MetricTypeSum:       "otel_metrics_sum",
-			model.MetricTypeHistogram: "otel_metrics_histogram",
-			model.MetricTypeSummary:   "otel_metrics_summary",
+			model.MetricTypeGauge:           "otel_metrics_gauge",
+			model.MetricTypeSum:             "otel_metrics_sum",
+			model.MetricTypeHistogram:       "otel_metrics_histogram",
+			model.MetricTypeSummary:         "otel_metrics_summary",
+			model.MetricTypeNativeHistogram: "otel_metrics_exponential_histogram",
 		},
 	}
 }
 
 // BuildQuery implements QueryBuilder interface
-func (b *Builder) BuildQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType model.MetricType) (string, error) {
+func (b *Builder) BuildQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType model.MetricType) (string, []interface{}, error) {
+	return b.build(ctx, mint, maxt, matchers, metricType, "TimeUnix")
+}
+
+// BuildStreamQuery implements QueryBuilder interface
+func (b *Builder) BuildStreamQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType model.MetricType) (string, []interface{}, error) {
+	return b.build(ctx, mint, maxt, matchers, metricType, "MetricName, ServiceName, Attributes, TimeUnix")
+}
+
+func (b *Builder) build(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher, metricType model.MetricType, orderBy string) (string, []interface{}, error) {
 	table, ok := b.tableMapping[metricType]
 	if !ok {
-		return "", fmt.Errorf("unsupported metric type: %s", metricType)
+		return "", nil, fmt.Errorf("unsupported metric type: %s", metricType)
 	}
 
 	queryParts := []string{
 		"SELECT TimeUnix as timestamp,",
 	}
 
-	// Add value selection based on metric type
+	// Add value selection based on metric type. Cumulative types (anything
+	// but Gauge) also select StartTimeUnix so the reader's counter adjuster
+	// can detect resets and mark stale series. Histogram and Summary are
+	// read as a plain cumulative value (their Sum field), the same as Sum
+	// itself: convertToReadResponse has no bucket/quantile handling, and the
+	// Writer never populates BucketCounts/ExplicitBounds/Count/
+	// ValueAtQuantiles.* either, so selecting them here would just be dead
+	// columns the reader can't scan into anything.
 	switch metricType {
-	case model.MetricTypeGauge, model.MetricTypeSum:
+	case model.MetricTypeGauge:
 		queryParts = append(queryParts, "Value as value,")
-	case model.MetricTypeHistogram:
-		// For histograms, we need to handle bucket counts
-		queryParts = append(queryParts, "Sum as value, BucketCounts, ExplicitBounds,")
-	case model.MetricTypeSummary:
-		// For summaries, we need to handle quantiles
-		queryParts = append(queryParts, "Sum as value, Count, ValueAtQuantiles.Quantile, ValueAtQuantiles.Value,")
+	case model.MetricTypeSum:
+		queryParts = append(queryParts, "Value as value, StartTimeUnix,")
+	case model.MetricTypeHistogram, model.MetricTypeSummary:
+		queryParts = append(queryParts, "Sum as value, StartTimeUnix,")
+	case model.MetricTypeNativeHistogram:
+		// Sparse exponential-bucket buckets: dense offset+counts per side,
+		// re-encoded to prompb's span form with absolute float counts by the
+		// histogram package.
+		queryParts = append(queryParts, "Count, Sum as value, Scale, ZeroCount, ZeroThreshold, PositiveOffset, PositiveBucketCounts, NegativeOffset, NegativeBucketCounts, StartTimeUnix,")
 	}
 
 	// Add label columns
@@ -61,102 +87,144 @@ func (b *Builder) BuildQuery(ctx context.Context, mint, maxt time.Time, matchers
 	queryParts = append(queryParts, fmt.Sprintf("FROM %s", table))
 
 	// Add WHERE clause
-	whereClauses := []string{
-		fmt.Sprintf("TimeUnix BETWEEN toDateTime64('%s', 9) AND toDateTime64('%s', 9)",
-			mint.Format(time.RFC3339Nano),
-			maxt.Format(time.RFC3339Nano)),
-	}
+	args := []interface{}{mint, maxt}
+	whereClauses := []string{"TimeUnix BETWEEN ? AND ?"}
 
 	// Handle label matchers
-	labelClauses := b.buildLabelMatchers(matchers)
-	if len(labelClauses) > 0 {
-		whereClauses = append(whereClauses, labelClauses...)
+	labelClauses, labelArgs, err := b.buildLabelMatchers(matchers)
+	if err != nil {
+		return "", nil, err
 	}
+	whereClauses = append(whereClauses, labelClauses...)
+	args = append(args, labelArgs...)
 
 	queryParts = append(queryParts, "WHERE "+strings.Join(whereClauses, " AND "))
 
 	// Add ORDER BY clause
+	queryParts = append(queryParts, "ORDER BY "+orderBy)
+
+	return strings.Join(queryParts, " "), args, nil
+}
+
+// BuildExemplarQuery builds a parameterized query against
+// otel_metrics_exemplars for the /api/v1/query_exemplars remote-read
+// variant. Unlike BuildQuery, there's no metricType to look up a table
+// for: exemplars live in one table regardless of which otel_metrics_*
+// table the sample they're attached to came from.
+func (b *Builder) BuildExemplarQuery(ctx context.Context, mint, maxt time.Time, matchers []*labels.Matcher) (string, []interface{}, error) {
+	queryParts := []string{
+		"SELECT TimeUnix as timestamp, Value as value, MetricName, ServiceName, Attributes, ResourceAttributes, FilteredAttributes",
+		"FROM otel_metrics_exemplars",
+	}
+
+	args := []interface{}{mint, maxt}
+	whereClauses := []string{"TimeUnix BETWEEN ? AND ?"}
+
+	labelClauses, labelArgs, err := b.buildLabelMatchers(matchers)
+	if err != nil {
+		return "", nil, err
+	}
+	whereClauses = append(whereClauses, labelClauses...)
+	args = append(args, labelArgs...)
+
+	queryParts = append(queryParts, "WHERE "+strings.Join(whereClauses, " AND "))
 	queryParts = append(queryParts, "ORDER BY TimeUnix")
 
-	return strings.Join(queryParts, " "), nil
+	return strings.Join(queryParts, " "), args, nil
 }
 
-// buildLabelMatchers converts Prometheus label matchers to ClickHouse WHERE conditions
-func (b *Builder) buildLabelMatchers(matchers []*labels.Matcher) []string {
+// buildLabelMatchers converts Prometheus label matchers to ClickHouse WHERE
+// conditions and their bind args, in the order the placeholders appear.
+func (b *Builder) buildLabelMatchers(matchers []*labels.Matcher) ([]string, []interface{}, error) {
 	var conditions []string
+	var args []interface{}
 
 	for _, m := range matchers {
+		var (
+			condition string
+			condArgs  []interface{}
+			err       error
+		)
+
 		switch m.Name {
 		case "__name__":
 			// Handle metric name matcher
-			conditions = append(conditions, b.buildMetricNameMatcher(m))
+			condition, condArgs = b.buildMetricNameMatcher(m)
 		case "service_name":
 			// Handle service name matcher
-			conditions = append(conditions, b.buildServiceNameMatcher(m))
+			condition, condArgs = b.buildServiceNameMatcher(m)
 		default:
 			// Handle attribute matchers
-			conditions = append(conditions, b.buildAttributeMatcher(m))
+			condition, condArgs, err = b.buildAttributeMatcher(m)
+		}
+		if err != nil {
+			return nil, nil, err
 		}
+
+		conditions = append(conditions, condition)
+		args = append(args, condArgs...)
 	}
 
-	return conditions
+	return conditions, args, nil
 }
 
-func (b *Builder) buildMetricNameMatcher(m *labels.Matcher) string {
+func (b *Builder) buildMetricNameMatcher(m *labels.Matcher) (string, []interface{}) {
 	switch m.Type {
 	case labels.MatchEqual:
-		return fmt.Sprintf("MetricName = '%s'", escapeString(m.Value))
+		return "MetricName = ?", []interface{}{m.Value}
 	case labels.MatchNotEqual:
-		return fmt.Sprintf("MetricName != '%s'", escapeString(m.Value))
+		return "MetricName != ?", []interface{}{m.Value}
 	case labels.MatchRegexp:
-		return fmt.Sprintf("match(MetricName, '%s')", escapeString(m.Value))
+		return "match(MetricName, ?)", []interface{}{m.Value}
 	case labels.MatchNotRegexp:
-		return fmt.Sprintf("NOT match(MetricName, '%s')", escapeString(m.Value))
+		return "NOT match(MetricName, ?)", []interface{}{m.Value}
 	default:
-		return ""
+		return "", nil
 	}
 }
 
-func (b *Builder) buildServiceNameMatcher(m *labels.Matcher) string {
+func (b *Builder) buildServiceNameMatcher(m *labels.Matcher) (string, []interface{}) {
 	switch m.Type {
 	case labels.MatchEqual:
-		return fmt.Sprintf("ServiceName = '%s'", escapeString(m.Value))
+		return "ServiceName = ?", []interface{}{m.Value}
 	case labels.MatchNotEqual:
-		return fmt.Sprintf("ServiceName != '%s'", escapeString(m.Value))
+		return "ServiceName != ?", []interface{}{m.Value}
 	case labels.MatchRegexp:
-		return fmt.Sprintf("match(ServiceName, '%s')", escapeString(m.Value))
+		return "match(ServiceName, ?)", []interface{}{m.Value}
 	case labels.MatchNotRegexp:
-		return fmt.Sprintf("NOT match(ServiceName, '%s')", escapeString(m.Value))
+		return "NOT match(ServiceName, ?)", []interface{}{m.Value}
 	default:
-		return ""
+		return "", nil
 	}
 }
 
-func (b *Builder) buildAttributeMatcher(m *labels.Matcher) string {
-	// Check both ResourceAttributes and Attributes maps
+func (b *Builder) buildAttributeMatcher(m *labels.Matcher) (string, []interface{}, error) {
+	// Check both ResourceAttributes and Attributes maps. The key is a map
+	// subscript, which ClickHouse won't let us bind as a parameter, so it is
+	// validated against identifierRe rather than escaped.
+	if !identifierRe.MatchString(m.Name) {
+		return "", nil, fmt.Errorf("invalid label name %q", m.Name)
+	}
+
+	resourceKey := fmt.Sprintf("ResourceAttributes['%s']", m.Name)
+	attrKey := fmt.Sprintf("Attributes['%s']", m.Name)
+	resourceContains := fmt.Sprintf("mapContains(ResourceAttributes, '%s')", m.Name)
+	attrContains := fmt.Sprintf("mapContains(Attributes, '%s')", m.Name)
+
 	switch m.Type {
 	case labels.MatchEqual:
-		return fmt.Sprintf("(mapContains(ResourceAttributes, '%s') AND ResourceAttributes['%s'] = '%s') OR (mapContains(Attributes, '%s') AND Attributes['%s'] = '%s')",
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value),
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value))
+		return fmt.Sprintf("(%s AND %s = ?) OR (%s AND %s = ?)", resourceContains, resourceKey, attrContains, attrKey),
+			[]interface{}{m.Value, m.Value}, nil
 	case labels.MatchNotEqual:
-		return fmt.Sprintf("(NOT mapContains(ResourceAttributes, '%s') OR ResourceAttributes['%s'] != '%s') AND (NOT mapContains(Attributes, '%s') OR Attributes['%s'] != '%s')",
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value),
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value))
+		return fmt.Sprintf("(NOT %s OR %s != ?) AND (NOT %s OR %s != ?)", resourceContains, resourceKey, attrContains, attrKey),
+			[]interface{}{m.Value, m.Value}, nil
 	case labels.MatchRegexp:
-		return fmt.Sprintf("((mapContains(ResourceAttributes, '%s') AND match(ResourceAttributes['%s'], '%s')) OR (mapContains(Attributes, '%s') AND match(Attributes['%s'], '%s')))",
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value),
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value))
+		return fmt.Sprintf("((%s AND match(%s, ?)) OR (%s AND match(%s, ?)))", resourceContains, resourceKey, attrContains, attrKey),
+			[]interface{}{m.Value, m.Value}, nil
 	case labels.MatchNotRegexp:
-		return fmt.Sprintf("(NOT mapContains(ResourceAttributes, '%s') OR NOT match(ResourceAttributes['%s'], '%s')) AND (NOT mapContains(Attributes, '%s') OR NOT match(Attributes['%s'], '%s'))",
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value),
-			escapeString(m.Name), escapeString(m.Name), escapeString(m.Value))
+		return fmt.Sprintf("(NOT %s OR NOT match(%s, ?)) AND (NOT %s OR NOT match(%s, ?))", resourceContains, resourceKey, attrContains, attrKey),
+			[]interface{}{m.Value, m.Value}, nil
 	default:
-		return ""
+		return "", nil, nil
 	}
 }
-
-// escapeString escapes special characters in strings for ClickHouse SQL
-func escapeString(s string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(s, "'", "\\'"), "\\", "\\\\")
-}
@@ -23,6 +23,7 @@ func TestBuildQuery(t *testing.T) {
 		wantErr    bool
 		wantFrom   string
 		wantWhere  []string
+		wantArgs   []interface{}
 	}{
 		{
 			name:       "Gauge metric with no matchers",
@@ -31,8 +32,9 @@ func TestBuildQuery(t *testing.T) {
 			wantErr:    false,
 			wantFrom:   "FROM otel_metrics_gauge",
 			wantWhere: []string{
-				"TimeUnix BETWEEN",
+				"TimeUnix BETWEEN ? AND ?",
 			},
+			wantArgs: []interface{}{mint, maxt},
 		},
 		{
 			name:       "Sum metric with matchers",
@@ -44,10 +46,11 @@ func TestBuildQuery(t *testing.T) {
 			wantErr:  false,
 			wantFrom: "FROM otel_metrics_sum",
 			wantWhere: []string{
-				"TimeUnix BETWEEN",
-				"MetricName = 'http_requests_total'",
-				"ServiceName = 'api_service'",
+				"TimeUnix BETWEEN ? AND ?",
+				"MetricName = ?",
+				"ServiceName = ?",
 			},
+			wantArgs: []interface{}{mint, maxt, "http_requests_total", "api_service"},
 		},
 		{
 			name:       "Histogram metric with matchers",
@@ -58,9 +61,10 @@ func TestBuildQuery(t *testing.T) {
 			wantErr:  false,
 			wantFrom: "FROM otel_metrics_histogram",
 			wantWhere: []string{
-				"TimeUnix BETWEEN",
-				"match(ResourceAttributes['env'], 'prod|staging')",
+				"TimeUnix BETWEEN ? AND ?",
+				"match(ResourceAttributes['env'], ?)",
 			},
+			wantArgs: []interface{}{mint, maxt, "prod|staging", "prod|staging"},
 		},
 		{
 			name:       "Summary metric with matchers",
@@ -71,9 +75,24 @@ func TestBuildQuery(t *testing.T) {
 			wantErr:  false,
 			wantFrom: "FROM otel_metrics_summary",
 			wantWhere: []string{
-				"TimeUnix BETWEEN",
-				"ResourceAttributes['region'] != 'us-west'",
+				"TimeUnix BETWEEN ? AND ?",
+				"ResourceAttributes['region'] != ?",
 			},
+			wantArgs: []interface{}{mint, maxt, "us-west", "us-west"},
+		},
+		{
+			name:       "Native histogram metric with matchers",
+			metricType: model.MetricTypeNativeHistogram,
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "__name__", "request_latency_seconds"),
+			},
+			wantErr:  false,
+			wantFrom: "FROM otel_metrics_exponential_histogram",
+			wantWhere: []string{
+				"TimeUnix BETWEEN ? AND ?",
+				"MetricName = ?",
+			},
+			wantArgs: []interface{}{mint, maxt, "request_latency_seconds"},
 		},
 		{
 			name:       "Unsupported metric type",
@@ -81,11 +100,19 @@ func TestBuildQuery(t *testing.T) {
 			matchers:   []*labels.Matcher{},
 			wantErr:    true,
 		},
+		{
+			name:       "Invalid label name rejected",
+			metricType: model.MetricTypeGauge,
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "bad'name", "x"),
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			query, err := builder.BuildQuery(ctx, mint, maxt, tt.matchers, tt.metricType)
+			query, args, err := builder.BuildQuery(ctx, mint, maxt, tt.matchers, tt.metricType)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("BuildQuery() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -103,25 +130,101 @@ func TestBuildQuery(t *testing.T) {
 						t.Errorf("BuildQuery() query = %v, wantWhere %v", query, clause)
 					}
 				}
+				if len(args) != len(tt.wantArgs) {
+					t.Fatalf("BuildQuery() args = %v, want %v", args, tt.wantArgs)
+				}
+				for i := range args {
+					if args[i] != tt.wantArgs[i] {
+						t.Errorf("BuildQuery() args[%d] = %v, want %v", i, args[i], tt.wantArgs[i])
+					}
+				}
 			}
 		})
 	}
 }
 
+func TestBuildQuery_HistogramSummaryProjectSumAndStartTimeOnly(t *testing.T) {
+	builder := NewBuilder()
+	ctx := context.Background()
+	mint := time.Now().Add(-time.Hour)
+	maxt := time.Now()
+
+	for _, metricType := range []model.MetricType{model.MetricTypeHistogram, model.MetricTypeSummary} {
+		t.Run(string(metricType), func(t *testing.T) {
+			query, _, err := builder.BuildQuery(ctx, mint, maxt, nil, metricType)
+			if err != nil {
+				t.Fatalf("BuildQuery() unexpected error: %v", err)
+			}
+			if !strings.Contains(query, "Sum as value, StartTimeUnix,") {
+				t.Errorf("BuildQuery() query = %v, want \"Sum as value, StartTimeUnix,\"", query)
+			}
+			for _, col := range []string{"BucketCounts", "ExplicitBounds", "ValueAtQuantiles"} {
+				if strings.Contains(query, col) {
+					t.Errorf("BuildQuery() query = %v, must not select %s (reader has nowhere to scan it)", query, col)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildStreamQuery_OrdersBySeries(t *testing.T) {
+	builder := NewBuilder()
+	ctx := context.Background()
+	mint := time.Now().Add(-time.Hour)
+	maxt := time.Now()
+
+	query, _, err := builder.BuildStreamQuery(ctx, mint, maxt, nil, model.MetricTypeGauge)
+	if err != nil {
+		t.Fatalf("BuildStreamQuery() unexpected error: %v", err)
+	}
+	if !strings.Contains(query, "ORDER BY MetricName, ServiceName, Attributes, TimeUnix") {
+		t.Errorf("BuildStreamQuery() query = %v, want series-ordered ORDER BY", query)
+	}
+}
+
+func TestBuildExemplarQuery(t *testing.T) {
+	builder := NewBuilder()
+	ctx := context.Background()
+	mint := time.Now().Add(-time.Hour)
+	maxt := time.Now()
+
+	query, args, err := builder.BuildExemplarQuery(ctx, mint, maxt, []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "request_latency_seconds"),
+	})
+	if err != nil {
+		t.Fatalf("BuildExemplarQuery() unexpected error: %v", err)
+	}
+	if !strings.Contains(query, "FROM otel_metrics_exemplars") {
+		t.Errorf("BuildExemplarQuery() query = %v, want FROM otel_metrics_exemplars", query)
+	}
+	wantArgs := []interface{}{mint, maxt, "request_latency_seconds"}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("BuildExemplarQuery() args = %v, want %v", args, wantArgs)
+	}
+	for i := range args {
+		if args[i] != wantArgs[i] {
+			t.Errorf("BuildExemplarQuery() args[%d] = %v, want %v", i, args[i], wantArgs[i])
+		}
+	}
+}
+
 func TestBuildLabelMatchers(t *testing.T) {
 	builder := NewBuilder()
 
 	tests := []struct {
-		name     string
-		matchers []*labels.Matcher
-		want     []string
+		name      string
+		matchers  []*labels.Matcher
+		want      []string
+		wantArgs  []interface{}
+		expectErr bool
 	}{
 		{
 			name: "Single matcher",
 			matchers: []*labels.Matcher{
 				labels.MustNewMatcher(labels.MatchEqual, "service_name", "api_service"),
 			},
-			want: []string{"ServiceName = 'api_service'"},
+			want:     []string{"ServiceName = ?"},
+			wantArgs: []interface{}{"api_service"},
 		},
 		{
 			name: "Multiple matchers",
@@ -129,27 +232,43 @@ func TestBuildLabelMatchers(t *testing.T) {
 				labels.MustNewMatcher(labels.MatchEqual, "__name__", "http_requests_total"),
 				labels.MustNewMatcher(labels.MatchEqual, "service_name", "api_service"),
 			},
-			want: []string{"MetricName = 'http_requests_total'", "ServiceName = 'api_service'"},
+			want:     []string{"MetricName = ?", "ServiceName = ?"},
+			wantArgs: []interface{}{"http_requests_total", "api_service"},
 		},
 		{
 			name: "Regexp matcher",
 			matchers: []*labels.Matcher{
 				labels.MustNewMatcher(labels.MatchRegexp, "env", "prod|staging"),
 			},
-			want: []string{"((mapContains(ResourceAttributes, 'env') AND match(ResourceAttributes['env'], 'prod|staging')) OR (mapContains(Attributes, 'env') AND match(Attributes['env'], 'prod|staging')))"},
+			want:     []string{"((mapContains(ResourceAttributes, 'env') AND match(ResourceAttributes['env'], ?)) OR (mapContains(Attributes, 'env') AND match(Attributes['env'], ?)))"},
+			wantArgs: []interface{}{"prod|staging", "prod|staging"},
 		},
 		{
 			name: "NotEqual matcher",
 			matchers: []*labels.Matcher{
 				labels.MustNewMatcher(labels.MatchNotEqual, "region", "us-west"),
 			},
-			want: []string{"(NOT mapContains(ResourceAttributes, 'region') OR ResourceAttributes['region'] != 'us-west') AND (NOT mapContains(Attributes, 'region') OR Attributes['region'] != 'us-west')"},
+			want:     []string{"(NOT mapContains(ResourceAttributes, 'region') OR ResourceAttributes['region'] != ?) AND (NOT mapContains(Attributes, 'region') OR Attributes['region'] != ?)"},
+			wantArgs: []interface{}{"us-west", "us-west"},
+		},
+		{
+			name: "Invalid label name",
+			matchers: []*labels.Matcher{
+				labels.MustNewMatcher(labels.MatchEqual, "bad'name", "x"),
+			},
+			expectErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := builder.buildLabelMatchers(tt.matchers)
+			got, gotArgs, err := builder.buildLabelMatchers(tt.matchers)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("buildLabelMatchers() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expectErr {
+				return
+			}
 			if len(got) != len(tt.want) {
 				t.Errorf("buildLabelMatchers() got = %v, want %v", got, tt.want)
 				return
@@ -159,6 +278,14 @@ func TestBuildLabelMatchers(t *testing.T) {
 					t.Errorf("buildLabelMatchers() got = %v, want %v", got, tt.want)
 				}
 			}
+			if len(gotArgs) != len(tt.wantArgs) {
+				t.Fatalf("buildLabelMatchers() args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+			for i := range gotArgs {
+				if gotArgs[i] != tt.wantArgs[i] {
+					t.Errorf("buildLabelMatchers() args[%d] = %v, want %v", i, gotArgs[i], tt.wantArgs[i])
+				}
+			}
 		})
 	}
 }
@@ -0,0 +1,131 @@
+package reader
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/cache"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultCacheTTL bounds how long a cached query result is reused for.
+const DefaultCacheTTL = 30 * time.Second
+
+// CachedReader wraps a Reader with a result cache, so that identical
+// remote_read queries (same matchers, time range, and resolved metric type)
+// reuse a cached ClickHouse result instead of re-querying. Concurrent
+// identical queries are coalesced via singleflight so only one ClickHouse
+// round-trip runs per key even under a thundering herd of queriers.
+// StreamRead is passed straight through to reader, uncached.
+type CachedReader struct {
+	reader    *Reader
+	resolver  model.MetricTypeResolver
+	cache     cache.Cache
+	metrics   *cache.Metrics
+	ttl       time.Duration
+	freshness time.Duration
+	group     singleflight.Group
+}
+
+// NewCachedReader wraps reader with c, caching results for ttl and bypassing
+// (or refreshing) the cache for any query whose range comes within
+// freshness of "now", since those overlap the head block and a cached
+// result could miss still-arriving samples. metrics may be nil, in which
+// case unregistered defaults are used (mirroring writer.NewWriter's
+// nil-Metrics convention).
+func NewCachedReader(reader *Reader, resolver model.MetricTypeResolver, c cache.Cache, metrics *cache.Metrics, ttl, freshness time.Duration) *CachedReader {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if metrics == nil {
+		metrics = cache.NewMetrics(nil)
+	}
+	return &CachedReader{
+		reader:    reader,
+		resolver:  resolver,
+		cache:     c,
+		metrics:   metrics,
+		ttl:       ttl,
+		freshness: freshness,
+	}
+}
+
+// Read implements storage.QueryableClient
+func (c *CachedReader) Read(ctx context.Context, req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+	response := &prompb.ReadResponse{
+		Results: make([]*prompb.QueryResult, 0, len(req.Queries)),
+	}
+	for _, q := range req.Queries {
+		result, err := c.readOne(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response, nil
+}
+
+// StreamRead implements streaming remote reads by delegating straight to
+// the wrapped Reader: streaming query results are never cached.
+func (c *CachedReader) StreamRead(ctx context.Context, req *prompb.ReadRequest, w io.Writer) error {
+	return c.reader.StreamRead(ctx, req, w)
+}
+
+func (c *CachedReader) readOne(ctx context.Context, q *prompb.Query) (*prompb.QueryResult, error) {
+	maxt := time.UnixMilli(q.EndTimestampMs)
+	if c.freshness > 0 && time.Since(maxt) < c.freshness {
+		return c.query(ctx, q)
+	}
+
+	matchers, err := convertLabelMatchers(q.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	metricType, err := c.resolver.ResolveMetricType(ctx, matchers)
+	if err != nil {
+		return nil, err
+	}
+	mint := time.UnixMilli(q.StartTimestampMs)
+	key := cache.Key(matchers, mint, maxt, metricType)
+
+	if cached, ok, err := c.cache.Get(ctx, key); err == nil && ok {
+		result := &prompb.QueryResult{}
+		if err := proto.Unmarshal(cached, result); err == nil {
+			return result, nil
+		}
+	}
+
+	v, err, shared := c.group.Do(key, func() (interface{}, error) {
+		return c.query(ctx, q)
+	})
+	if shared {
+		c.metrics.SingleflightShares.Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := v.(*prompb.QueryResult)
+
+	if encoded, err := proto.Marshal(result); err == nil {
+		_ = c.cache.Set(ctx, key, encoded, c.ttl)
+	}
+
+	return result, nil
+}
+
+func (c *CachedReader) query(ctx context.Context, q *prompb.Query) (*prompb.QueryResult, error) {
+	resp, err := c.reader.Read(ctx, &prompb.ReadRequest{Queries: []*prompb.Query{q}})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results[0], nil
+}
+
+// Type implements storage.QueryableClient
+func (c *CachedReader) Type() string {
+	return c.reader.Type()
+}
@@ -0,0 +1,125 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/cache"
+	chclient "github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+)
+
+// countingConn is a driver.Conn that counts how many times Query is
+// actually issued, so tests can assert the cache suppressed (or didn't
+// suppress) a ClickHouse round-trip.
+type countingConn struct {
+	driver.Conn
+	queries int
+}
+
+func (c *countingConn) Query(ctx context.Context, query string, args ...interface{}) (driver.Rows, error) {
+	c.queries++
+	return &mockRows{rows: [][]interface{}{
+		{int64(1000), 1.0, "up", "api", map[string]string{}, map[string]string{}},
+	}}, nil
+}
+
+func newTestRequest(endMs int64) *prompb.ReadRequest {
+	return &prompb.ReadRequest{Queries: []*prompb.Query{{
+		StartTimestampMs: 0,
+		EndTimestampMs:   endMs,
+		Matchers:         []*prompb.LabelMatcher{{Type: prompb.LabelMatcher_EQ, Name: "__name__", Value: "up"}},
+	}}}
+}
+
+func TestCachedReader_CoalescesRepeatedQueries(t *testing.T) {
+	conn := &countingConn{}
+	c := chclient.NewClientFromConn(conn, nil)
+	r := NewReader(c, stubStreamBuilder{}, stubStreamResolver{})
+
+	resultCache := cache.NewLRUCache(16, time.Minute, cache.NewMetrics(nil))
+	metrics := cache.NewMetrics(nil)
+	cr := NewCachedReader(r, stubStreamResolver{}, resultCache, metrics, time.Minute, 0)
+
+	req := newTestRequest(1000)
+
+	if _, err := cr.Read(context.Background(), req); err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if _, err := cr.Read(context.Background(), req); err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+
+	if conn.queries != 1 {
+		t.Errorf("Read() issued %d ClickHouse queries, want 1 (second should hit cache)", conn.queries)
+	}
+}
+
+// blockingConn is a driver.Conn whose Query blocks until release is closed,
+// so a test can force two concurrent readOne calls to overlap and take the
+// singleflight-shared path.
+type blockingConn struct {
+	driver.Conn
+	release chan struct{}
+}
+
+func (c *blockingConn) Query(ctx context.Context, query string, args ...interface{}) (driver.Rows, error) {
+	<-c.release
+	return &mockRows{rows: [][]interface{}{
+		{int64(1000), 1.0, "up", "api", map[string]string{}, map[string]string{}},
+	}}, nil
+}
+
+func TestCachedReader_NilMetricsDefaults(t *testing.T) {
+	conn := &blockingConn{release: make(chan struct{})}
+	c := chclient.NewClientFromConn(conn, nil)
+	r := NewReader(c, stubStreamBuilder{}, stubStreamResolver{})
+
+	resultCache := cache.NewLRUCache(16, time.Minute, cache.NewMetrics(nil))
+	cr := NewCachedReader(r, stubStreamResolver{}, resultCache, nil, time.Minute, 0)
+
+	req := newTestRequest(1000)
+
+	// Two concurrent identical queries: the second shares the first's
+	// in-flight ClickHouse call via singleflight, which increments
+	// c.metrics.SingleflightShares directly, so a nil metrics here would
+	// panic rather than default.
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := cr.Read(context.Background(), req)
+			done <- err
+		}()
+	}
+	close(conn.release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Read() unexpected error: %v", err)
+		}
+	}
+}
+
+func TestCachedReader_BypassesCacheWithinFreshnessWindow(t *testing.T) {
+	conn := &countingConn{}
+	c := chclient.NewClientFromConn(conn, nil)
+	r := NewReader(c, stubStreamBuilder{}, stubStreamResolver{})
+
+	resultCache := cache.NewLRUCache(16, time.Minute, cache.NewMetrics(nil))
+	metrics := cache.NewMetrics(nil)
+	cr := NewCachedReader(r, stubStreamResolver{}, resultCache, metrics, time.Minute, time.Hour)
+
+	req := newTestRequest(time.Now().UnixMilli())
+
+	if _, err := cr.Read(context.Background(), req); err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	if _, err := cr.Read(context.Background(), req); err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+
+	if conn.queries != 2 {
+		t.Errorf("Read() issued %d ClickHouse queries, want 2 (both within freshness window, cache bypassed)", conn.queries)
+	}
+}
@@ -0,0 +1,90 @@
+package reader
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+)
+
+// ExemplarReader implements storage.ExemplarQueryable for ClickHouse,
+// serving the /api/v1/query_exemplars remote-read variant from
+// otel_metrics_exemplars.
+type ExemplarReader struct {
+	client *client.ExemplarsClient
+}
+
+// NewExemplarReader creates an ExemplarReader backed by c.
+func NewExemplarReader(c *client.ExemplarsClient) *ExemplarReader {
+	return &ExemplarReader{client: c}
+}
+
+// ExemplarQuerier implements storage.ExemplarQueryable.
+func (r *ExemplarReader) ExemplarQuerier(ctx context.Context) (storage.ExemplarQuerier, error) {
+	return &exemplarQuerier{ctx: ctx, client: r.client}, nil
+}
+
+type exemplarQuerier struct {
+	ctx    context.Context
+	client *client.ExemplarsClient
+}
+
+// Select implements storage.ExemplarQuerier. Each matcher set is queried
+// independently and, if it has any exemplars, becomes its own
+// exemplar.QueryResult (a set with none is omitted); SeriesLabels is
+// approximated from that set's equality matchers only, since a
+// regexp-matched series can't be named exactly from its matchers alone.
+func (q *exemplarQuerier) Select(start, end int64, matchers ...[]*labels.Matcher) ([]exemplar.QueryResult, error) {
+	results := make([]exemplar.QueryResult, 0, len(matchers))
+	for _, set := range matchers {
+		exemplars, err := q.client.QueryExemplars(q.ctx, set, time.UnixMilli(start), time.UnixMilli(end))
+		if err != nil {
+			return nil, err
+		}
+		if len(exemplars) == 0 {
+			continue
+		}
+
+		result := exemplar.QueryResult{
+			SeriesLabels: equalityLabels(set),
+			Exemplars:    make([]exemplar.Exemplar, 0, len(exemplars)),
+		}
+		for _, e := range exemplars {
+			result.Exemplars = append(result.Exemplars, exemplar.Exemplar{
+				Labels: convertPromLabels(e.Labels),
+				Value:  e.Value,
+				Ts:     e.Timestamp,
+				HasTs:  true,
+			})
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// equalityLabels approximates a series' labels from its equality matchers,
+// since a regexp or negative matcher doesn't pin down an exact label value.
+func equalityLabels(matchers []*labels.Matcher) labels.Labels {
+	lbls := make(labels.Labels, 0, len(matchers))
+	for _, m := range matchers {
+		if m.Type == labels.MatchEqual {
+			lbls = append(lbls, labels.Label{Name: m.Name, Value: m.Value})
+		}
+	}
+	sort.Sort(lbls)
+	return lbls
+}
+
+func convertPromLabels(lbls []prompb.Label) labels.Labels {
+	result := make(labels.Labels, 0, len(lbls))
+	for _, l := range lbls {
+		result = append(result, labels.Label{Name: l.Name, Value: l.Value})
+	}
+	sort.Sort(result)
+	return result
+}
@@ -0,0 +1,88 @@
+package reader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+)
+
+// fakeExemplarConn is a minimal driver.Conn that always returns rows,
+// for testing exemplarQuerier.Select without a real ClickHouse connection.
+type fakeExemplarConn struct {
+	driver.Conn
+	rows *fakeDriverRows
+}
+
+func (c *fakeExemplarConn) Query(ctx context.Context, query string, args ...interface{}) (driver.Rows, error) {
+	return c.rows, nil
+}
+
+// fakeDriverRows is a minimal driver.Rows backed by an in-memory slice of
+// rows, each a slice of column values in the order exemplarQuerier.Select
+// scans them.
+type fakeDriverRows struct {
+	driver.Rows
+	rows [][]interface{}
+	idx  int
+}
+
+func (f *fakeDriverRows) Next() bool {
+	f.idx++
+	return f.idx <= len(f.rows)
+}
+
+func (f *fakeDriverRows) Scan(dest ...interface{}) error {
+	row := f.rows[f.idx-1]
+	for i, d := range dest {
+		switch v := d.(type) {
+		case *int64:
+			*v = row[i].(int64)
+		case *float64:
+			*v = row[i].(float64)
+		case *string:
+			*v = row[i].(string)
+		case *map[string]string:
+			*v = row[i].(map[string]string)
+		}
+	}
+	return nil
+}
+
+func (f *fakeDriverRows) Close() error { return nil }
+
+func TestExemplarQuerier_Select(t *testing.T) {
+	rows := &fakeDriverRows{rows: [][]interface{}{
+		{
+			int64(1000), 0.42, "http_request_duration_seconds", "api",
+			map[string]string{}, map[string]string{}, map[string]string{"trace_id": "abc123"},
+		},
+	}}
+	c := client.NewExemplarsClientFromConn(&fakeExemplarConn{rows: rows}, nil)
+	reader := NewExemplarReader(c)
+
+	querier, err := reader.ExemplarQuerier(context.Background())
+	if err != nil {
+		t.Fatalf("ExemplarQuerier() unexpected error: %v", err)
+	}
+
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "http_request_duration_seconds"),
+	}
+	results, err := querier.Select(0, time.Now().UnixMilli(), matchers)
+	if err != nil {
+		t.Fatalf("Select() unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Select() = %d results, want 1", len(results))
+	}
+	if len(results[0].Exemplars) != 1 {
+		t.Fatalf("Select() = %d exemplars, want 1", len(results[0].Exemplars))
+	}
+	if got := results[0].Exemplars[0].Labels.Get("trace_id"); got != "abc123" {
+		t.Errorf("Select() exemplar trace_id = %q, want abc123", got)
+	}
+}
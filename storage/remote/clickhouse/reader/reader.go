@@ -2,27 +2,54 @@ package reader
 
 import (
 	"context"
-	"golang.org/x/exp/maps"
-	"regexp"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/adjuster"
 	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/fingerprint"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/histogram"
 	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
-	"github.com/prometheus/prometheus/storage/remote/clickhouse/query"
 )
 
 type Reader struct {
-	client  client.Client
-	builder query.Builder
+	client          *client.Client
+	builder         model.QueryBuilder
+	resolver        model.MetricTypeResolver
+	adjuster        *adjuster.Adjuster
+	streamFrameSize int
 }
 
-func NewReader(client client.Client, builder query.Builder) *Reader {
+func NewReader(client *client.Client, builder model.QueryBuilder, resolver model.MetricTypeResolver) *Reader {
+	return NewReaderWithStaleness(client, builder, resolver, adjuster.DefaultStalenessInterval)
+}
+
+// NewReaderWithStaleness is like NewReader but lets callers override how
+// long a series may go unseen, as of a query's end time, before it is
+// marked stale.
+func NewReaderWithStaleness(client *client.Client, builder model.QueryBuilder, resolver model.MetricTypeResolver, staleness time.Duration) *Reader {
 	return &Reader{
-		client:  client,
-		builder: builder,
+		client:          client,
+		builder:         builder,
+		resolver:        resolver,
+		adjuster:        adjuster.New(staleness),
+		streamFrameSize: DefaultStreamFrameSize,
+	}
+}
+
+// AcceptedResponseTypes reports the remote-read response types this Reader
+// can produce, for a handler to intersect against a ReadRequest's
+// AcceptedResponseTypes when deciding whether to call StreamRead or Read.
+// STREAMED_XOR_CHUNKS is listed first since it's preferred when supported.
+func (r *Reader) AcceptedResponseTypes() []prompb.ReadRequest_ResponseType {
+	return []prompb.ReadRequest_ResponseType{
+		prompb.ReadRequest_STREAMED_XOR_CHUNKS,
+		prompb.ReadRequest_SAMPLES,
 	}
 }
 
@@ -37,19 +64,27 @@ func (r *Reader) Read(ctx context.Context, req *prompb.ReadRequest) (*prompb.Rea
 		if err != nil {
 			return nil, err
 		}
-		clickhouseQuery, err := r.builder.BuildQuery(ctx, time.UnixMilli(promQLQuery.StartTimestampMs),
-			time.UnixMilli(promQLQuery.EndTimestampMs),
-			matchers, getMetricType(promQLQuery))
+		metricType, err := r.resolver.ResolveMetricType(ctx, matchers)
 		if err != nil {
 			return nil, err
 		}
-
-		result, err := r.client.Query(ctx, clickhouseQuery)
+		maxt := time.UnixMilli(promQLQuery.EndTimestampMs)
+		clickhouseQuery, args, err := r.builder.BuildQuery(ctx, time.UnixMilli(promQLQuery.StartTimestampMs),
+			maxt, matchers, metricType)
 		if err != nil {
 			return nil, err
 		}
-		readResponse, err := convertToReadResponse(result)
 
+		result, err := r.client.Query(ctx, clickhouseQuery, args...)
+		if err != nil {
+			return nil, err
+		}
+		var readResponse *prompb.QueryResult
+		if metricType == model.MetricTypeNativeHistogram {
+			readResponse, err = convertToHistogramReadResponse(result, r.adjuster, maxt)
+		} else {
+			readResponse, err = convertToReadResponse(result, metricType, r.adjuster, maxt)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -59,140 +94,227 @@ func (r *Reader) Read(ctx context.Context, req *prompb.ReadRequest) (*prompb.Rea
 	return response, nil
 }
 
-func getMetricType(pQuery *prompb.Query) model.MetricType {
-	var pseudoQuery string
-	var metricName string
-	var queryLabels map[string]string
-	matchers := pQuery.Matchers
-
-	for _, matcher := range matchers {
-		pseudoQuery += matcher.Value
-		if matcher.Name == "__name__" {
-			metricName = matcher.Value
+func convertLabelMatchers(matchers []*prompb.LabelMatcher) ([]*labels.Matcher, error) {
+	var result []*labels.Matcher
+	for _, m := range matchers {
+		matcher, err := labels.NewMatcher(labels.MatchType(m.Type), m.Name, m.Value)
+		if err != nil {
+			return nil, err
 		}
-		queryLabels[matcher.Name] = matcher.Value
+		result = append(result, matcher)
 	}
-	return inferMetricType(pseudoQuery, metricName, queryLabels)
+	return result, nil
 }
 
-// Here's how we will infer -
-// Metric Names and Common Conventions:
-// Counters => otel_metrics_sum
-// Gauge => otel_metrics_gauge
-// Histogram => otel_metric_histogram
-// Summary => otel_metric_summary
-//
-// _total suffix: Metrics ending with _total are very often counters. This is a strong indicator that it is a _sum.
-// _count suffix: Similar to _total, _count often suggests a counter or a histogram's count. we default to _sum.
-// _sum suffix: This is often used for histograms and summaries to represent the sum of observed values.
-// _bucket suffix: This is a clear indicator of a histogram.
-// 2. PromQL Functions:
-//
-// rate() or irate(): These functions are specifically designed for calculating the per-second rate of increase of counters.
-// If these functions are used, it's almost certainly a counter.
-// increase(): This function calculates the increase in the value of a counter over a specified time range. Again, strongly indicates a counter.
-// histogram_quantile(): This function is exclusively used with histograms.
-// sum(rate(...)) or sum(increase(...)): Applying sum() after rate() or increase() suggests you're aggregating rates of multiple counters.
-// count_over_time(): While applicable to any time series, it's often used with counters to count events over a time window.
-// deriv(): This function calculates the per-second derivative of a time series. It can be applied to gauges, but it's less commonly used on counters.
-// 3. Label Analysis:
-// le label (in histograms): The presence of the le (less than or equal to) label is a definitive sign of a histogram's buckets.
-// 4. Combining Clues:
-//
-// The most reliable approach is to combine these clues. For example:
-//
-// If a metric ends with _total and is used with rate(), it's almost certainly a counter.
-// If a metric has the le label and is used with histogram_quantile(), it's definitely a histogram.
-func inferMetricType(query string, metricName string, labels map[string]string) model.MetricType {
-	query = strings.ToLower(query)
-
-	if strings.HasSuffix(metricName, "_total") || strings.HasSuffix(metricName, "_count") {
-		if strings.Contains(query, "rate(") || strings.Contains(query, "irate(") || strings.Contains(query, "increase(") {
-			return model.MetricTypeSum
-		}
-		if strings.Contains(query, "sum_over_time(") || strings.Contains(query, "count_over_time(") {
-			return model.MetricTypeSum
-		}
-
-	}
-
-	// Check for common functions used with counters even without _total/_count
-	// we do this at the last
-	counterFunctionsRegexMap := map[string]string{
-		"rate":            `rate\(`,
-		"irate":           `irate\(`,
-		"increase":        `increase\(`,
-		"sum_over_time":   `sum_over_time\(`,
-		"count_over_time": `count_over_time\(`,
-	}
-	for _, fn := range maps.Keys(counterFunctionsRegexMap) {
-		if strings.Contains(query, fn) {
-			// Use a regex to check if function is used on the current metric
-			re := regexp.MustCompile(fn + `\s*\(\s*` + regexp.QuoteMeta(metricName) + `\b`)
-			if re.MatchString(query) {
-				return model.MetricTypeGauge
-			}
-		}
-	}
+// seriesKey identifies a series by the same columns the counter adjuster
+// keys its per-series state on: metric name, service name, and a
+// canonicalized fingerprint of each attribute map (the maps themselves
+// aren't comparable, so can't be used as map keys directly).
+type seriesKey struct {
+	metricName, serviceName, attributesKey, resourceAttributesKey string
+}
 
-	if strings.HasSuffix(metricName, "_bucket") || labels["le"] != "" {
-		if strings.Contains(query, "histogram_quantile(") {
-			return model.MetricTypeHistogram
-		}
+func (k seriesKey) fingerprint() string {
+	return k.metricName + "|" + k.serviceName + "|" + k.attributesKey + "|" + k.resourceAttributesKey
+}
+
+// otelResourceLabelNames maps the resource attributes writer.classify uses
+// to stash job/instance back to their Prometheus label names.
+var otelResourceLabelNames = map[string]string{
+	"service.name":        "job",
+	"service.instance.id": "instance",
+}
+
+// normalizeOTelLabelName converts an OTel attribute name into a valid
+// Prometheus label name, following the same convention as the OTel
+// Collector's prometheusremotewrite exporter: characters outside
+// [A-Za-z0-9_] become underscores, and a leading digit is prefixed with
+// one too.
+func normalizeOTelLabelName(name string) string {
+	if promName, ok := otelResourceLabelNames[name]; ok {
+		return promName
 	}
 
-	if strings.HasSuffix(metricName, "_sum") {
-		// Could be summary or histogram, need more context if possible
-		if strings.Contains(query, "histogram_quantile(") {
-			return model.MetricTypeSummary
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
 		}
-		return model.MetricTypeSum
 	}
+	return b.String()
+}
 
-	if strings.Contains(query, "deriv(") {
-		return model.MetricTypeGauge
+// buildLabels reconstructs a Prometheus label set from the OTel-shaped
+// columns: metricName becomes __name__, serviceName becomes job (mirroring
+// writer.classify, which stores job as the ServiceName column), and each
+// Attributes/ResourceAttributes entry becomes its own label, normalized via
+// normalizeOTelLabelName.
+func buildLabels(metricName, serviceName string, attributes, resourceAttributes map[string]string) labels.Labels {
+	set := make(map[string]string, len(attributes)+len(resourceAttributes)+2)
+	set[labels.MetricName] = metricName
+	for name, value := range resourceAttributes {
+		set[normalizeOTelLabelName(name)] = value
+	}
+	for name, value := range attributes {
+		set[normalizeOTelLabelName(name)] = value
+	}
+	if serviceName != "" {
+		set["job"] = serviceName
 	}
 
-	// Default to gauge if no strong indicators
-	return model.MetricTypeGauge
+	result := make(labels.Labels, 0, len(set))
+	for name, value := range set {
+		result = append(result, labels.Label{Name: name, Value: value})
+	}
+	sort.Sort(result)
+	return result
 }
 
-func convertLabelMatchers(matchers []*prompb.LabelMatcher) ([]*labels.Matcher, error) {
-	var result []*labels.Matcher
-	for _, m := range matchers {
-		matcher, err := labels.NewMatcher(labels.MatchType(m.Type), m.Name, m.Value)
-		if err != nil {
+// convertToReadResponse scans all rows into per-series sample slices, then,
+// for cumulative metric types, runs each series through adj to inject reset
+// samples across counter restarts and stale markers for series that have
+// gone quiet.
+func convertToReadResponse(result client.Rows, metricType model.MetricType, adj *adjuster.Adjuster, queryEnd time.Time) (*prompb.QueryResult, error) {
+	samplesByKey := make(map[seriesKey][]prompb.Sample)
+	startTimeByKey := make(map[seriesKey]time.Time)
+	attributesByKey := make(map[seriesKey]map[string]string)
+	resourceAttributesByKey := make(map[seriesKey]map[string]string)
+	metricNameByKey := make(map[seriesKey]string)
+	var order []seriesKey
+
+	for result.Next() {
+		var timestamp int64
+		var value float64
+		var metricName, serviceName string
+		var attributes, resourceAttributes map[string]string
+		var startTimeUnix time.Time
+
+		// query.Builder selects StartTimeUnix (for cumulative types) ahead of
+		// the label columns, right after the value column(s); dest must match
+		// that projection order, not the order fields are declared above.
+		dest := []interface{}{&timestamp, &value}
+		if metricType != model.MetricTypeGauge {
+			dest = append(dest, &startTimeUnix)
+		}
+		dest = append(dest, &metricName, &serviceName, &attributes, &resourceAttributes)
+		if err := result.Scan(dest...); err != nil {
 			return nil, err
 		}
-		result = append(result, matcher)
+
+		key := seriesKey{metricName, serviceName, fingerprint.Of(attributes), fingerprint.Of(resourceAttributes)}
+		if _, ok := samplesByKey[key]; !ok {
+			order = append(order, key)
+			metricNameByKey[key] = metricName
+			attributesByKey[key] = attributes
+			resourceAttributesByKey[key] = resourceAttributes
+		}
+		samplesByKey[key] = append(samplesByKey[key], prompb.Sample{Timestamp: timestamp, Value: value})
+		startTimeByKey[key] = startTimeUnix
 	}
-	return result, nil
+
+	tsResults := make([]*prompb.TimeSeries, 0, len(order))
+	for _, key := range order {
+		samples := samplesByKey[key]
+		if metricType != model.MetricTypeGauge && adj != nil {
+			samples = adj.Adjust(key.fingerprint(), startTimeByKey[key], samples, queryEnd)
+		}
+		lbls := buildLabels(metricNameByKey[key], key.serviceName, attributesByKey[key], resourceAttributesByKey[key])
+		tsResults = append(tsResults, &prompb.TimeSeries{
+			Labels:  convertLabels(lbls),
+			Samples: samples,
+		})
+	}
+	return &prompb.QueryResult{
+		Timeseries: tsResults,
+	}, nil
 }
 
-func convertToReadResponse(result client.Rows) (*prompb.QueryResult, error) {
-	var tsResults []*prompb.TimeSeries
+// convertToHistogramReadResponse is convertToReadResponse's native-histogram
+// counterpart. otel_metrics_exponential_histogram stores one full dense
+// bucket snapshot per row, so unlike the classical metric types there's no
+// per-bucket state to accumulate across rows, only each row's dense
+// PositiveBucketCounts/NegativeBucketCounts to re-encode back into prompb's
+// span form, with absolute float counts (a float histogram), via the
+// histogram package. Resets and staleness are
+// still tracked with adj, via Reset/Stale directly since Adjust itself
+// works in terms of prompb.Sample, which a Histogram isn't.
+func convertToHistogramReadResponse(result client.Rows, adj *adjuster.Adjuster, queryEnd time.Time) (*prompb.QueryResult, error) {
+	histogramsByKey := make(map[seriesKey][]*prompb.Histogram)
+	attributesByKey := make(map[seriesKey]map[string]string)
+	resourceAttributesByKey := make(map[seriesKey]map[string]string)
+	metricNameByKey := make(map[seriesKey]string)
+	var order []seriesKey
+
 	for result.Next() {
-		var samples []prompb.Sample
 		var timestamp int64
-		var value float64
-		var metricName, serviceName, attributes, resourceAttributes string
-		err := result.Scan(&timestamp, &value, &metricName, &serviceName, &attributes, &resourceAttributes)
+		var count, sum, zeroCount, zeroThreshold float64
+		var scale, positiveOffset, negativeOffset int32
+		var positiveBucketCounts, negativeBucketCounts []float64
+		var startTimeUnix time.Time
+		var metricName, serviceName string
+		var attributes, resourceAttributes map[string]string
 
-		if err != nil {
+		if err := result.Scan(
+			&timestamp, &count, &sum, &scale, &zeroCount, &zeroThreshold,
+			&positiveOffset, &positiveBucketCounts, &negativeOffset, &negativeBucketCounts,
+			&startTimeUnix, &metricName, &serviceName, &attributes, &resourceAttributes,
+		); err != nil {
 			return nil, err
 		}
-		samples = append(samples, prompb.Sample{
-			Timestamp: timestamp,
-			Value:     value,
+
+		key := seriesKey{metricName, serviceName, fingerprint.Of(attributes), fingerprint.Of(resourceAttributes)}
+		if _, ok := histogramsByKey[key]; !ok {
+			order = append(order, key)
+			metricNameByKey[key] = metricName
+			attributesByKey[key] = attributes
+			resourceAttributesByKey[key] = resourceAttributes
+		}
+
+		resetHint := prompb.Histogram_NO
+		if adj.Reset(key.fingerprint(), startTimeUnix) {
+			resetHint = prompb.Histogram_YES
+		}
+
+		positiveSpans, positiveCounts := histogram.CollapseCounts(histogram.Buckets{Offset: positiveOffset, Counts: positiveBucketCounts})
+		negativeSpans, negativeCounts := histogram.CollapseCounts(histogram.Buckets{Offset: negativeOffset, Counts: negativeBucketCounts})
+
+		histogramsByKey[key] = append(histogramsByKey[key], &prompb.Histogram{
+			Count:          &prompb.Histogram_CountFloat{CountFloat: count},
+			Sum:            sum,
+			Schema:         scale,
+			ZeroThreshold:  zeroThreshold,
+			ZeroCount:      &prompb.Histogram_ZeroCountFloat{ZeroCountFloat: zeroCount},
+			PositiveSpans:  positiveSpans,
+			PositiveCounts: positiveCounts,
+			NegativeSpans:  negativeSpans,
+			NegativeCounts: negativeCounts,
+			ResetHint:      resetHint,
+			Timestamp:      timestamp,
 		})
+	}
+
+	tsResults := make([]*prompb.TimeSeries, 0, len(order))
+	for _, key := range order {
+		histograms := histogramsByKey[key]
+		if last := histograms[len(histograms)-1]; adj.Stale(time.UnixMilli(last.Timestamp), queryEnd) {
+			histograms = append(histograms, &prompb.Histogram{
+				Sum:       math.Float64frombits(value.StaleNaN),
+				Count:     &prompb.Histogram_CountFloat{CountFloat: 0},
+				Timestamp: queryEnd.UnixMilli(),
+			})
+		}
+		lbls := buildLabels(metricNameByKey[key], key.serviceName, attributesByKey[key], resourceAttributesByKey[key])
 		tsResults = append(tsResults, &prompb.TimeSeries{
-			Labels: []prompb.Label{
-				{Name: "MetricName", Value: metricName},
-				{Name: "ServiceName", Value: serviceName},
-				{Name: "Attributes", Value: attributes},
-				{Name: "ResourceAttributes", Value: resourceAttributes},
-			},
-			Samples: samples,
+			Labels:     convertLabels(lbls),
+			Histograms: histograms,
 		})
 	}
 	return &prompb.QueryResult{
@@ -200,10 +322,10 @@ func convertToReadResponse(result client.Rows) (*prompb.QueryResult, error) {
 	}, nil
 }
 
-func convertLabels(lbls labels.Labels) []*prompb.Label {
-	var result []*prompb.Label
+func convertLabels(lbls labels.Labels) []prompb.Label {
+	result := make([]prompb.Label, 0, len(lbls))
 	for _, lbl := range lbls {
-		result = append(result, &prompb.Label{
+		result = append(result, prompb.Label{
 			Name:  lbl.Name,
 			Value: lbl.Value,
 		})
@@ -1,11 +1,18 @@
 package reader
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/adjuster"
 	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/query"
 )
 
 func TestConvertLabelMatchers(t *testing.T) {
@@ -63,74 +70,270 @@ func labelsMatchersEqual(a, b []*labels.Matcher) bool {
 	return true
 }
 
-func TestInferMetricType(t *testing.T) {
-	tests := []struct {
-		query      string
-		metricName string
-		labels     map[string]string
-		expected   model.MetricType
-	}{
-		{
-			query:      "rate(metric_total[5m])",
-			metricName: "metric_total",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeSum,
-		},
-		{
-			query:      "histogram_quantile(0.95, metric_bucket)",
-			metricName: "metric_bucket",
-			labels:     map[string]string{"le": "0.95"},
-			expected:   model.MetricTypeHistogram,
-		},
-		{
-			query:      "sum_over_time(metric_sum[5m])",
-			metricName: "metric_sum",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
-		},
-		{
-			query:      "metric_sum[5m]",
-			metricName: "metric_sum",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeSum,
-		},
-		{
-			query:      "deriv(metric)",
-			metricName: "metric",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
-		},
-		{
-			query:      "rate(metric[5m])",
-			metricName: "metric",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
-		},
+// fakeRows is a minimal client.Rows backed by an in-memory slice of rows,
+// each a slice of column values in the order convertToReadResponse scans
+// them.
+type fakeRows struct {
+	rows [][]interface{}
+	idx  int
+}
+
+func (f *fakeRows) Next() bool {
+	f.idx++
+	return f.idx <= len(f.rows)
+}
+
+func (f *fakeRows) Scan(dest ...interface{}) error {
+	row := f.rows[f.idx-1]
+	for i, d := range dest {
+		switch v := d.(type) {
+		case *int64:
+			*v = row[i].(int64)
+		case *int32:
+			*v = row[i].(int32)
+		case *float64:
+			*v = row[i].(float64)
+		case *[]float64:
+			*v = row[i].([]float64)
+		case *string:
+			*v = row[i].(string)
+		case *time.Time:
+			*v = row[i].(time.Time)
+		case *map[string]string:
+			*v = row[i].(map[string]string)
+		}
+	}
+	return nil
+}
+
+func (f *fakeRows) Close() error                              { return nil }
+func (f *fakeRows) Columns() ([]string, error)                { return nil, nil }
+func (f *fakeRows) ColumnTypes() ([]driver.ColumnType, error) { return nil, nil }
+
+func TestConvertToReadResponse_GroupsRowsIntoSeries(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
+		{int64(1000), 1.0, "http_requests_total", "api", map[string]string{}, map[string]string{}},
+		{int64(2000), 2.0, "http_requests_total", "api", map[string]string{}, map[string]string{}},
+	}}
+
+	result, err := convertToReadResponse(rows, model.MetricTypeGauge, adjuster.New(time.Minute), time.UnixMilli(2000))
+	if err != nil {
+		t.Fatalf("convertToReadResponse() unexpected error: %v", err)
+	}
+	if len(result.Timeseries) != 1 {
+		t.Fatalf("convertToReadResponse() = %d series, want 1", len(result.Timeseries))
+	}
+	if len(result.Timeseries[0].Samples) != 2 {
+		t.Errorf("convertToReadResponse() = %d samples, want 2", len(result.Timeseries[0].Samples))
+	}
+}
+
+func TestConvertToReadResponse_AdjustsCumulativeSeries(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
+		{int64(60000), 5.0, time.UnixMilli(0), "http_requests_total", "api", map[string]string{}, map[string]string{}},
+	}}
+
+	adj := adjuster.New(time.Minute)
+	result, err := convertToReadResponse(rows, model.MetricTypeSum, adj, time.UnixMilli(60000))
+	if err != nil {
+		t.Fatalf("convertToReadResponse() unexpected error: %v", err)
+	}
+	if len(result.Timeseries) != 1 || len(result.Timeseries[0].Samples) != 1 {
+		t.Fatalf("convertToReadResponse() = %+v, want 1 series with 1 sample on first sight", result.Timeseries)
+	}
+}
+
+func TestConvertToReadResponse_ExpandsAttributesIntoLabels(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
+		{int64(1000), 1.0, "http_requests_total", "api", map[string]string{"http.method": "GET"}, map[string]string{"service.name": "api", "service.instance.id": "api-1"}},
+	}}
+
+	result, err := convertToReadResponse(rows, model.MetricTypeGauge, adjuster.New(time.Minute), time.UnixMilli(1000))
+	if err != nil {
+		t.Fatalf("convertToReadResponse() unexpected error: %v", err)
+	}
+	if len(result.Timeseries) != 1 {
+		t.Fatalf("convertToReadResponse() = %d series, want 1", len(result.Timeseries))
+	}
+
+	got := make(map[string]string)
+	for _, l := range result.Timeseries[0].Labels {
+		got[l.Name] = l.Value
+	}
+	want := map[string]string{
+		"__name__":    "http_requests_total",
+		"job":         "api",
+		"instance":    "api-1",
+		"http_method": "GET",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("convertToReadResponse() labels = %v, want %s=%s", got, name, value)
+		}
+	}
+}
+
+func TestConvertToHistogramReadResponse_CollapsesDenseBucketsIntoSpans(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
 		{
-			query:      "metric",
-			metricName: "metric",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
+			int64(1000), 4.0, 12.5, int32(3), 0.0, 0.001,
+			int32(0), []float64{1, 3}, int32(0), []float64{},
+			time.UnixMilli(0), "request_latency_seconds", "api",
+			map[string]string{}, map[string]string{},
 		},
+	}}
+
+	result, err := convertToHistogramReadResponse(rows, adjuster.New(time.Minute), time.UnixMilli(1000))
+	if err != nil {
+		t.Fatalf("convertToHistogramReadResponse() unexpected error: %v", err)
+	}
+	if len(result.Timeseries) != 1 {
+		t.Fatalf("convertToHistogramReadResponse() = %d series, want 1", len(result.Timeseries))
+	}
+
+	histograms := result.Timeseries[0].Histograms
+	if len(histograms) != 1 {
+		t.Fatalf("convertToHistogramReadResponse() = %d histograms, want 1", len(histograms))
+	}
+	h := histograms[0]
+	if h.Sum != 12.5 || h.Schema != 3 {
+		t.Errorf("convertToHistogramReadResponse() histogram = %+v, want Sum=12.5 Schema=3", h)
+	}
+	wantSpans := []prompb.BucketSpan{{Offset: 0, Length: 2}}
+	if !reflect.DeepEqual(h.PositiveSpans, wantSpans) {
+		t.Errorf("convertToHistogramReadResponse() PositiveSpans = %+v, want %+v", h.PositiveSpans, wantSpans)
+	}
+}
+
+// TestConvertToHistogramReadResponse_RoundTripsSchemaZeroBucketAndBothSides
+// covers the case the native-histogram request actually asked for: a
+// schema=2 histogram with a non-zero zero bucket and dense buckets on both
+// the positive and negative side, so a regression in negative-offset math
+// or zero-count column order (distinct from the positive-only case covered
+// above) would fail here instead of only in production.
+func TestConvertToHistogramReadResponse_RoundTripsSchemaZeroBucketAndBothSides(t *testing.T) {
+	rows := &fakeRows{rows: [][]interface{}{
 		{
-			query:      "rate(chi_clickhouse_metric_DiskDataBytes[5m])",
-			metricName: "chi_clickhouse_metric_DiskDataBytes",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
-		}, {
-			query:      "sum(rate(node_network_receive_bytes_total{cluster=\"demo-acc-cluster\", job=\"integrations/node_exporter\"}[$__rate_interval])) by (instance)",
-			metricName: "chi_clickhouse_metric_DiskDataBytes",
-			labels:     map[string]string{},
-			expected:   model.MetricTypeGauge,
+			int64(1000), 11.0, 12.5, int32(2), 7.0, 0.001,
+			int32(0), []float64{1, 3}, int32(1), []float64{2, 5},
+			time.UnixMilli(0), "request_latency_seconds", "api",
+			map[string]string{}, map[string]string{},
 		},
+	}}
+
+	result, err := convertToHistogramReadResponse(rows, adjuster.New(time.Minute), time.UnixMilli(1000))
+	if err != nil {
+		t.Fatalf("convertToHistogramReadResponse() unexpected error: %v", err)
+	}
+	if len(result.Timeseries) != 1 || len(result.Timeseries[0].Histograms) != 1 {
+		t.Fatalf("convertToHistogramReadResponse() = %+v, want 1 series with 1 histogram", result.Timeseries)
 	}
 
-	for _, test := range tests {
-		t.Run(test.query, func(t *testing.T) {
-			result := inferMetricType(test.query, test.metricName, test.labels)
-			if result != test.expected {
-				t.Errorf("expected %v, got %v", test.expected, result)
+	h := result.Timeseries[0].Histograms[0]
+	if h.Schema != 2 {
+		t.Errorf("convertToHistogramReadResponse() Schema = %v, want 2", h.Schema)
+	}
+	if zc, ok := h.ZeroCount.(*prompb.Histogram_ZeroCountFloat); !ok || zc.ZeroCountFloat != 7.0 {
+		t.Errorf("convertToHistogramReadResponse() ZeroCount = %+v, want ZeroCountFloat=7", h.ZeroCount)
+	}
+
+	wantPositiveSpans := []prompb.BucketSpan{{Offset: 0, Length: 2}}
+	wantPositiveCounts := []float64{1, 3}
+	if !reflect.DeepEqual(h.PositiveSpans, wantPositiveSpans) || !reflect.DeepEqual(h.PositiveCounts, wantPositiveCounts) {
+		t.Errorf("convertToHistogramReadResponse() Positive = %+v/%+v, want %+v/%+v", h.PositiveSpans, h.PositiveCounts, wantPositiveSpans, wantPositiveCounts)
+	}
+
+	wantNegativeSpans := []prompb.BucketSpan{{Offset: 1, Length: 2}}
+	wantNegativeCounts := []float64{2, 5}
+	if !reflect.DeepEqual(h.NegativeSpans, wantNegativeSpans) || !reflect.DeepEqual(h.NegativeCounts, wantNegativeCounts) {
+		t.Errorf("convertToHistogramReadResponse() Negative = %+v/%+v, want %+v/%+v", h.NegativeSpans, h.NegativeCounts, wantNegativeSpans, wantNegativeCounts)
+	}
+}
+
+// TestConvertToReadResponse_MatchesBuilderColumnOrder drives query.Builder's
+// actual SELECT projection, rather than a hand-authored fixture, through
+// convertToReadResponse's Scan destination list for every non-native-
+// histogram metric type, so a future change to one without the other fails
+// here instead of only against real ClickHouse.
+func TestConvertToReadResponse_MatchesBuilderColumnOrder(t *testing.T) {
+	b := query.NewBuilder()
+	mint, maxt := time.Unix(0, 0), time.Unix(3600, 0)
+
+	for _, metricType := range []model.MetricType{
+		model.MetricTypeGauge,
+		model.MetricTypeSum,
+		model.MetricTypeHistogram,
+		model.MetricTypeSummary,
+	} {
+		t.Run(string(metricType), func(t *testing.T) {
+			sql, _, err := b.BuildQuery(context.Background(), mint, maxt, nil, metricType)
+			if err != nil {
+				t.Fatalf("BuildQuery() unexpected error: %v", err)
+			}
+
+			row := fakeRowForColumns(t, selectedColumns(t, sql))
+			rows := &fakeRows{rows: [][]interface{}{row}}
+
+			// queryEnd is the fixture row's own timestamp, so cumulative
+			// types get no stale marker appended after it.
+			result, err := convertToReadResponse(rows, metricType, adjuster.New(time.Minute), time.UnixMilli(1000))
+			if err != nil {
+				t.Fatalf("convertToReadResponse() could not scan BuildQuery()'s own column order: %v", err)
+			}
+			if len(result.Timeseries) != 1 || len(result.Timeseries[0].Samples) != 1 {
+				t.Fatalf("convertToReadResponse() = %+v, want 1 series with 1 sample", result.Timeseries)
 			}
 		})
 	}
 }
+
+// selectedColumns extracts the projected column names, in order, from a
+// "SELECT col1, col2 as alias, ... FROM ..." query, dropping any "as alias".
+func selectedColumns(t *testing.T, sql string) []string {
+	t.Helper()
+	start := strings.Index(sql, "SELECT ")
+	end := strings.Index(sql, " FROM ")
+	if start == -1 || end == -1 {
+		t.Fatalf("selectedColumns: malformed query: %s", sql)
+	}
+	parts := strings.Split(sql[start+len("SELECT "):end], ",")
+	columns := make([]string, 0, len(parts))
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			// query.Builder ends the last projected column with a comma too.
+			continue
+		}
+		if i := strings.Index(name, " as "); i != -1 {
+			name = name[:i]
+		}
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// fakeRowForColumns builds one fakeRows row of dummy values typed to match
+// what convertToReadResponse scans for each ClickHouse column name.
+func fakeRowForColumns(t *testing.T, columns []string) []interface{} {
+	t.Helper()
+	row := make([]interface{}, len(columns))
+	for i, col := range columns {
+		switch col {
+		case "TimeUnix":
+			row[i] = int64(1000)
+		case "Value", "Sum":
+			row[i] = 5.0
+		case "StartTimeUnix":
+			row[i] = time.UnixMilli(0)
+		case "MetricName":
+			row[i] = "http_requests_total"
+		case "ServiceName":
+			row[i] = "api"
+		case "Attributes", "ResourceAttributes":
+			row[i] = map[string]string{}
+		default:
+			t.Fatalf("fakeRowForColumns: unhandled column %q", col)
+		}
+	}
+	return row
+}
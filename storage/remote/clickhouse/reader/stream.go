@@ -0,0 +1,225 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/fingerprint"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultStreamFrameSize bounds how many bytes of encoded chunk data
+// StreamRead buffers for a series before flushing a ChunkedReadResponse
+// frame, so a series spanning a large time range is sent incrementally
+// instead of being held in memory in full. Reader.streamFrameSize defaults
+// to this and is only overridden in tests.
+const DefaultStreamFrameSize = 1 << 20 // 1MiB
+
+// noopFlusher is used when the io.Writer passed to StreamRead doesn't
+// implement http.Flusher (e.g. in tests, writing to a bytes.Buffer).
+type noopFlusher struct{}
+
+func (noopFlusher) Flush() {}
+
+// StreamRead implements the STREAMED_XOR_CHUNKS remote-read response type.
+// Rows are scanned in series order (query.Builder.BuildStreamQuery orders
+// by MetricName, ServiceName, Attributes ahead of TimeUnix) and XOR-encoded
+// into prompb.Chunk frames via tsdb/chunkenc, flushed through w as soon as
+// a series' encoded chunk exceeds DefaultStreamFrameSize, so large time
+// ranges never need to be fully buffered.
+func (r *Reader) StreamRead(ctx context.Context, req *prompb.ReadRequest, w io.Writer) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		flusher = noopFlusher{}
+	}
+	cw := remote.NewChunkedWriter(w, flusher)
+
+	for queryIndex, promQLQuery := range req.Queries {
+		matchers, err := convertLabelMatchers(promQLQuery.Matchers)
+		if err != nil {
+			return err
+		}
+		metricType, err := r.resolver.ResolveMetricType(ctx, matchers)
+		if err != nil {
+			return err
+		}
+		if metricType == model.MetricTypeNativeHistogram {
+			// streamSeries only knows how to XOR-encode float samples into
+			// chunkenc.XORChunk; native histograms need their own chunk type
+			// and dense-bucket scan shape (see convertToHistogramReadResponse),
+			// neither of which this path has. Reject explicitly rather than
+			// let it fail deep inside Scan with a confusing column-count error.
+			return fmt.Errorf("reader: streamed chunked reads do not support native histograms; use Read instead")
+		}
+		maxt := time.UnixMilli(promQLQuery.EndTimestampMs)
+		sql, args, err := r.builder.BuildStreamQuery(ctx, time.UnixMilli(promQLQuery.StartTimestampMs), maxt, matchers, metricType)
+		if err != nil {
+			return err
+		}
+
+		rows, err := r.client.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		err = r.streamSeries(rows, metricType, maxt, int64(queryIndex), cw)
+		closeErr := rows.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+
+	return nil
+}
+
+// seriesChunk accumulates one series' XOR-encoded samples until it is
+// flushed, either because it exceeded the frame size budget or the series
+// changed.
+type seriesChunk struct {
+	key              seriesKey
+	metricName       string
+	attributes       map[string]string
+	resourceAttrs    map[string]string
+	startTime        time.Time
+	chunk            *chunkenc.XORChunk
+	appender         chunkenc.Appender
+	minTime, maxTime int64
+}
+
+func newSeriesChunk(key seriesKey, metricName string, attributes, resourceAttrs map[string]string, startTime time.Time) (*seriesChunk, error) {
+	chunk := chunkenc.NewXORChunk()
+	appender, err := chunk.Appender()
+	if err != nil {
+		return nil, fmt.Errorf("reader: creating chunk appender: %w", err)
+	}
+	return &seriesChunk{
+		key:           key,
+		metricName:    metricName,
+		attributes:    attributes,
+		resourceAttrs: resourceAttrs,
+		startTime:     startTime,
+		chunk:         chunk,
+		appender:      appender,
+	}, nil
+}
+
+func (s *seriesChunk) append(timestamp int64, value float64) {
+	s.appender.Append(timestamp, value)
+	if s.chunk.NumSamples() == 1 || timestamp < s.minTime {
+		s.minTime = timestamp
+	}
+	if timestamp > s.maxTime {
+		s.maxTime = timestamp
+	}
+}
+
+func (r *Reader) streamSeries(rows client.Rows, metricType model.MetricType, queryEnd time.Time, queryIndex int64, cw *remote.ChunkedWriter) error {
+	var current *seriesChunk
+
+	flush := func() error {
+		if current == nil || current.chunk.NumSamples() == 0 {
+			return nil
+		}
+		lbls := buildLabels(current.metricName, current.key.serviceName, current.attributes, current.resourceAttrs)
+		return writeChunkFrame(cw, lbls, current.chunk.Bytes(), current.minTime, current.maxTime, queryIndex)
+	}
+
+	finishSeries := func() error {
+		if current == nil {
+			return nil
+		}
+		if metricType != model.MetricTypeGauge && current.chunk.NumSamples() > 0 && r.adjuster.Stale(time.UnixMilli(current.maxTime), queryEnd) {
+			current.append(queryEnd.UnixMilli(), math.Float64frombits(value.StaleNaN))
+		}
+		return flush()
+	}
+
+	for rows.Next() {
+		var timestamp int64
+		var value float64
+		var metricName, serviceName string
+		var attributes, resourceAttributes map[string]string
+		var startTimeUnix time.Time
+
+		// query.Builder selects StartTimeUnix (for cumulative types) ahead of
+		// the label columns, right after the value column; dest must match
+		// that projection order, not the order fields are declared above.
+		dest := []interface{}{&timestamp, &value}
+		if metricType != model.MetricTypeGauge {
+			dest = append(dest, &startTimeUnix)
+		}
+		dest = append(dest, &metricName, &serviceName, &attributes, &resourceAttributes)
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		key := seriesKey{metricName, serviceName, fingerprint.Of(attributes), fingerprint.Of(resourceAttributes)}
+		if current == nil || current.key != key {
+			if err := finishSeries(); err != nil {
+				return err
+			}
+			var err error
+			current, err = newSeriesChunk(key, metricName, attributes, resourceAttributes, startTimeUnix)
+			if err != nil {
+				return err
+			}
+			if metricType != model.MetricTypeGauge && r.adjuster.Reset(key.fingerprint(), startTimeUnix) {
+				current.append(startTimeUnix.UnixMilli(), 0)
+			}
+		}
+
+		current.append(timestamp, value)
+
+		if len(current.chunk.Bytes()) >= r.streamFrameSize {
+			if err := flush(); err != nil {
+				return err
+			}
+			next, err := newSeriesChunk(key, current.metricName, current.attributes, current.resourceAttrs, current.startTime)
+			if err != nil {
+				return err
+			}
+			current = next
+		}
+	}
+
+	return finishSeries()
+}
+
+func writeChunkFrame(cw *remote.ChunkedWriter, lbls labels.Labels, data []byte, minTime, maxTime int64, queryIndex int64) error {
+	resp := &prompb.ChunkedReadResponse{
+		ChunkedSeries: []*prompb.ChunkedSeries{
+			{
+				Labels: convertLabels(lbls),
+				Chunks: []prompb.Chunk{
+					{
+						MinTimeMs: minTime,
+						MaxTimeMs: maxTime,
+						Type:      prompb.Chunk_XOR,
+						Data:      data,
+					},
+				},
+			},
+		},
+		QueryIndex: queryIndex,
+	}
+
+	b, err := proto.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("reader: marshaling chunked read response: %w", err)
+	}
+	_, err = cw.Write(b)
+	return err
+}
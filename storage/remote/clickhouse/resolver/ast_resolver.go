@@ -0,0 +1,183 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// rateFunctions are PromQL functions that, applied to a *_total or *_count
+// selector, confirm it's being used as a counter.
+var rateFunctions = map[string]bool{
+	"rate":     true,
+	"irate":    true,
+	"increase": true,
+	"deriv":    true,
+	"resets":   true,
+}
+
+// grafanaRangeVars swaps the Grafana template variables that dashboards put
+// where a range duration goes, which the PromQL parser rejects, for a fixed
+// duration; only the expression's shape matters for classification.
+var grafanaRangeVars = strings.NewReplacer(
+	"$__rate_interval", "5m",
+	"$__interval", "5m",
+	"$__range", "5m",
+)
+
+// ClassifyQuery parses a PromQL expression and returns the inferred
+// model.MetricType of every VectorSelector it references, keyed by metric
+// name. Unlike the matcher-only heuristics in LegacyHeuristicResolver, this
+// walks the real AST, so it correctly classifies nested expressions like
+// sum(rate(x_total{...}[$__rate_interval])) by (instance) that confuse a
+// regex over the raw query text.
+func ClassifyQuery(query string) (map[string]model.MetricType, error) {
+	expr, err := parser.ParseExpr(grafanaRangeVars.Replace(query))
+	if err != nil {
+		return nil, fmt.Errorf("resolver: parsing query %q: %w", query, err)
+	}
+
+	// First pass: record every selector's name and whether it carries a le
+	// or quantile label, since the summary _sum/_count pairing below needs
+	// to see all selectors before deciding.
+	type selector struct {
+		name          string
+		hasLe         bool
+		hasQuantile   bool
+		enclosingRate bool
+		enclosingHist bool
+	}
+	var selectors []selector
+
+	parser.Inspect(expr, func(node parser.Node, path []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		s := selector{name: vectorSelectorName(vs)}
+		for _, m := range vs.LabelMatchers {
+			switch m.Name {
+			case "le":
+				s.hasLe = true
+			case "quantile":
+				s.hasQuantile = true
+			}
+		}
+		for _, ancestor := range path {
+			call, ok := ancestor.(*parser.Call)
+			if !ok || call.Func == nil {
+				continue
+			}
+			if rateFunctions[call.Func.Name] {
+				s.enclosingRate = true
+			}
+			if call.Func.Name == "histogram_quantile" {
+				s.enclosingHist = true
+			}
+		}
+		selectors = append(selectors, s)
+		return nil
+	})
+
+	// baseNames tracks which "_sum"/"_count" stems also appear, to spot the
+	// base_sum + base_count pairing a summary's client library emits.
+	baseNames := make(map[string]bool, len(selectors))
+	for _, s := range selectors {
+		baseNames[s.name] = true
+	}
+
+	types := make(map[string]model.MetricType, len(selectors))
+	for _, s := range selectors {
+		if s.name == "" {
+			continue
+		}
+		types[s.name] = classifySelector(s.name, s.hasLe, s.hasQuantile, s.enclosingRate, s.enclosingHist, baseNames)
+	}
+	return types, nil
+}
+
+func classifySelector(name string, hasLe, hasQuantile, enclosingRate, enclosingHist bool, baseNames map[string]bool) model.MetricType {
+	if hasLe || enclosingHist || hasSuffix(name, "_bucket") {
+		return model.MetricTypeHistogram
+	}
+	if hasQuantile {
+		return model.MetricTypeSummary
+	}
+	if hasSuffix(name, "_sum") && baseNames[trimSuffix(name, "_sum")+"_count"] {
+		return model.MetricTypeSummary
+	}
+	if (hasSuffix(name, "_total") || hasSuffix(name, "_count") || hasSuffix(name, "_sum")) && enclosingRate {
+		return model.MetricTypeSum
+	}
+	return model.MetricTypeGauge
+}
+
+func vectorSelectorName(vs *parser.VectorSelector) string {
+	if vs.Name != "" {
+		return vs.Name
+	}
+	for _, m := range vs.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+func hasSuffix(s, suffix string) bool {
+	return len(s) >= len(suffix) && s[len(s)-len(suffix):] == suffix
+}
+
+func trimSuffix(s, suffix string) string {
+	return s[:len(s)-len(suffix)]
+}
+
+// ASTResolver resolves a single metric's type by parsing a synthetic PromQL
+// selector built from its matchers and classifying it with ClassifyQuery.
+// remote_read only hands Reader the matchers, not the original expression,
+// so ASTResolver never sees an enclosing rate()/histogram_quantile() call;
+// it mainly buys grammar-correct le/quantile label handling in place of the
+// legacy resolver's regexes, and is meant to sit ahead of
+// LegacyHeuristicResolver in a Chain, not replace it.
+type ASTResolver struct{}
+
+// NewASTResolver returns an ASTResolver.
+func NewASTResolver() *ASTResolver {
+	return &ASTResolver{}
+}
+
+// ResolveMetricType implements model.MetricTypeResolver.
+func (r *ASTResolver) ResolveMetricType(_ context.Context, matchers []*labels.Matcher) (model.MetricType, error) {
+	name := metricName(matchers)
+	if name == "" {
+		return "", fmt.Errorf("%w: no __name__ matcher in query", ErrUnresolved)
+	}
+	types, err := ClassifyQuery(syntheticSelector(name, matchers))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnresolved, err)
+	}
+	metricType, ok := types[name]
+	if !ok {
+		return "", fmt.Errorf("%w: no classification for metric %q", ErrUnresolved, name)
+	}
+	return metricType, nil
+}
+
+// syntheticSelector renders matchers back into PromQL selector syntax
+// (name{label="value",...}) so ClassifyQuery can parse it. The __name__
+// matcher is dropped since Name already carries it; the parser rejects a
+// selector that sets both.
+func syntheticSelector(name string, matchers []*labels.Matcher) string {
+	rest := make([]*labels.Matcher, 0, len(matchers))
+	for _, m := range matchers {
+		if m.Name != labels.MetricName {
+			rest = append(rest, m)
+		}
+	}
+	sel := &parser.VectorSelector{Name: name, LabelMatchers: rest}
+	return sel.String()
+}
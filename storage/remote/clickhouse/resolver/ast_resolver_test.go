@@ -0,0 +1,115 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		metric   string
+		expected model.MetricType
+	}{
+		{
+			name:     "rate over counter",
+			query:    "rate(http_requests_total[5m])",
+			metric:   "http_requests_total",
+			expected: model.MetricTypeSum,
+		},
+		{
+			name:     "nested sum(rate()) by, the case the legacy regex misclassifies",
+			query:    `sum(rate(node_network_receive_bytes_total{cluster="demo-acc-cluster", job="integrations/node_exporter"}[$__rate_interval])) by (instance)`,
+			metric:   "node_network_receive_bytes_total",
+			expected: model.MetricTypeSum,
+		},
+		{
+			name:     "histogram_quantile over bucket",
+			query:    "histogram_quantile(0.95, sum(rate(request_duration_seconds_bucket[5m])) by (le))",
+			metric:   "request_duration_seconds_bucket",
+			expected: model.MetricTypeHistogram,
+		},
+		{
+			name:     "bucket selector without histogram_quantile still implies histogram",
+			query:    "request_duration_seconds_bucket",
+			metric:   "request_duration_seconds_bucket",
+			expected: model.MetricTypeHistogram,
+		},
+		{
+			name:     "le matcher implies histogram",
+			query:    `request_duration_seconds_bucket{le="0.5"}`,
+			metric:   "request_duration_seconds_bucket",
+			expected: model.MetricTypeHistogram,
+		},
+		{
+			name:     "quantile matcher implies summary",
+			query:    `request_duration_seconds{quantile="0.5"}`,
+			metric:   "request_duration_seconds",
+			expected: model.MetricTypeSummary,
+		},
+		{
+			name:     "sum/count pair implies summary",
+			query:    "request_duration_seconds_sum / request_duration_seconds_count",
+			metric:   "request_duration_seconds_sum",
+			expected: model.MetricTypeSummary,
+		},
+		{
+			name:     "bare gauge",
+			query:    "up",
+			metric:   "up",
+			expected: model.MetricTypeGauge,
+		},
+		{
+			name:     "deriv over a gauge stays a gauge",
+			query:    "deriv(temperature_celsius[5m])",
+			metric:   "temperature_celsius",
+			expected: model.MetricTypeGauge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			types, err := ClassifyQuery(test.query)
+			if err != nil {
+				t.Fatalf("ClassifyQuery() unexpected error: %v", err)
+			}
+			if got := types[test.metric]; got != test.expected {
+				t.Errorf("ClassifyQuery()[%q] = %v, want %v", test.metric, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestClassifyQuery_ParseError(t *testing.T) {
+	if _, err := ClassifyQuery("sum(("); err == nil {
+		t.Fatal("ClassifyQuery() expected an error for an unparseable query")
+	}
+}
+
+func TestASTResolver_ResolveMetricType(t *testing.T) {
+	r := NewASTResolver()
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "request_duration_seconds_bucket"),
+		labels.MustNewMatcher(labels.MatchEqual, "le", "0.5"),
+	}
+
+	metricType, err := r.ResolveMetricType(context.Background(), matchers)
+	if err != nil {
+		t.Fatalf("ResolveMetricType() unexpected error: %v", err)
+	}
+	if metricType != model.MetricTypeHistogram {
+		t.Errorf("ResolveMetricType() = %v, want %v", metricType, model.MetricTypeHistogram)
+	}
+}
+
+func TestASTResolver_ResolveMetricType_NoName(t *testing.T) {
+	r := NewASTResolver()
+	_, err := r.ResolveMetricType(context.Background(), nil)
+	if err == nil {
+		t.Fatal("ResolveMetricType() expected an error when no __name__ matcher is present")
+	}
+}
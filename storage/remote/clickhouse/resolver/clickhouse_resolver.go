@@ -0,0 +1,113 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/lru"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// defaultCacheSize bounds the number of (metric, service) -> MetricType
+// entries kept in memory by ClickHouseResolver.
+const defaultCacheSize = 8192
+
+// defaultCacheTTL bounds how long a resolved metric type is trusted before
+// ClickHouseResolver re-checks ClickHouse, so a metric that changes table
+// (rare, but possible during a migration) is eventually picked up.
+const defaultCacheTTL = 10 * time.Minute
+
+// otelTables lists the otel_metrics_* tables to probe, in the order they
+// should be checked. Order matters only for tie-breaking when a metric name
+// happens to exist in more than one table.
+var otelTables = []struct {
+	table      string
+	metricType model.MetricType
+}{
+	{"otel_metrics_gauge", model.MetricTypeGauge},
+	{"otel_metrics_sum", model.MetricTypeSum},
+	{"otel_metrics_histogram", model.MetricTypeHistogram},
+	{"otel_metrics_summary", model.MetricTypeSummary},
+	{"otel_metrics_exponential_histogram", model.MetricTypeNativeHistogram},
+}
+
+type cacheKey struct {
+	metricName  string
+	serviceName string
+}
+
+// ClickHouseResolver determines a metric's type by checking which
+// otel_metrics_* table actually contains rows for it, using the OTel
+// metadata columns (MetricName, ServiceName) already present in the
+// tables. Results are cached since the table a metric lives in does not
+// change from query to query.
+type ClickHouseResolver struct {
+	client client.Client
+	cache  *lru.Cache[cacheKey, model.MetricType]
+}
+
+// NewClickHouseResolver creates a ClickHouseResolver backed by client. A
+// nil cache is never used directly; callers that need a custom size/TTL
+// should construct one with NewClickHouseResolverWithCache.
+func NewClickHouseResolver(c client.Client) *ClickHouseResolver {
+	return NewClickHouseResolverWithCache(c, defaultCacheSize, defaultCacheTTL)
+}
+
+// NewClickHouseResolverWithCache creates a ClickHouseResolver with an
+// explicit cache size and TTL.
+func NewClickHouseResolverWithCache(c client.Client, cacheSize int, cacheTTL time.Duration) *ClickHouseResolver {
+	return &ClickHouseResolver{
+		client: c,
+		cache:  lru.New[cacheKey, model.MetricType](cacheSize, cacheTTL),
+	}
+}
+
+// ResolveMetricType implements model.MetricTypeResolver.
+func (r *ClickHouseResolver) ResolveMetricType(ctx context.Context, matchers []*labels.Matcher) (model.MetricType, error) {
+	name := metricName(matchers)
+	if name == "" {
+		return "", fmt.Errorf("%w: no __name__ matcher in query", ErrUnresolved)
+	}
+	service := serviceName(matchers)
+
+	key := cacheKey{metricName: name, serviceName: service}
+	if metricType, ok := r.cache.Get(key); ok {
+		return metricType, nil
+	}
+
+	metricType, err := r.lookup(ctx, name, service)
+	if err != nil {
+		return "", err
+	}
+
+	r.cache.Set(key, metricType)
+	return metricType, nil
+}
+
+// lookup runs a cheap existence check against each otel_metrics_* table in
+// turn and returns the type of the first table that has a matching row.
+func (r *ClickHouseResolver) lookup(ctx context.Context, name, service string) (model.MetricType, error) {
+	for _, t := range otelTables {
+		query := fmt.Sprintf(
+			"SELECT MetricName FROM %s WHERE MetricName = ? AND (ServiceName = ? OR ? = '') LIMIT 1",
+			t.table,
+		)
+		rows, err := r.client.Query(ctx, query, name, service, service)
+		if err != nil {
+			return "", fmt.Errorf("resolver: querying %s: %w", t.table, err)
+		}
+
+		found := rows.Next()
+		closeErr := rows.Close()
+		if found {
+			return t.metricType, nil
+		}
+		if closeErr != nil {
+			return "", fmt.Errorf("resolver: closing rows from %s: %w", t.table, closeErr)
+		}
+	}
+	return "", fmt.Errorf("%w: metric %q not found in any otel_metrics_* table", ErrUnresolved, name)
+}
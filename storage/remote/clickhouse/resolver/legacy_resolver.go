@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// LegacyHeuristicResolver guesses a metric's type from its name and the
+// PromQL functions applied to it, the way this package used to before
+// ClickHouseResolver and StaticResolver existed. It never errors (it always
+// has a guess, defaulting to Gauge), so it is only useful as the last
+// resolver in a Chain, kept around for operators who opt into it over a
+// ClickHouse lookup or static metadata.
+type LegacyHeuristicResolver struct{}
+
+// NewLegacyHeuristicResolver returns a LegacyHeuristicResolver.
+func NewLegacyHeuristicResolver() *LegacyHeuristicResolver {
+	return &LegacyHeuristicResolver{}
+}
+
+// ResolveMetricType implements model.MetricTypeResolver.
+func (r *LegacyHeuristicResolver) ResolveMetricType(_ context.Context, matchers []*labels.Matcher) (model.MetricType, error) {
+	var pseudoQuery, name string
+	queryLabels := make(map[string]string, len(matchers))
+	for _, m := range matchers {
+		pseudoQuery += m.Value
+		if m.Name == labels.MetricName {
+			name = m.Value
+		}
+		queryLabels[m.Name] = m.Value
+	}
+	return inferMetricType(pseudoQuery, name, queryLabels), nil
+}
+
+// Here's how we will infer -
+// Metric Names and Common Conventions:
+// Counters => otel_metrics_sum
+// Gauge => otel_metrics_gauge
+// Histogram => otel_metric_histogram
+// Summary => otel_metric_summary
+//
+// _total suffix: Metrics ending with _total are very often counters. This is a strong indicator that it is a _sum.
+// _count suffix: Similar to _total, _count often suggests a counter or a histogram's count. we default to _sum.
+// _sum suffix: This is often used for histograms and summaries to represent the sum of observed values.
+// _bucket suffix: This is a clear indicator of a histogram.
+// 2. PromQL Functions:
+//
+// rate() or irate(): These functions are specifically designed for calculating the per-second rate of increase of counters.
+// If these functions are used, it's almost certainly a counter.
+// increase(): This function calculates the increase in the value of a counter over a specified time range. Again, strongly indicates a counter.
+// histogram_quantile(): This function is exclusively used with histograms.
+// sum(rate(...)) or sum(increase(...)): Applying sum() after rate() or increase() suggests you're aggregating rates of multiple counters.
+// count_over_time(): While applicable to any time series, it's often used with counters to count events over a time window.
+// deriv(): This function calculates the per-second derivative of a time series. It can be applied to gauges, but it's less commonly used on counters.
+// 3. Label Analysis:
+// le label (in histograms): The presence of the le (less than or equal to) label is a definitive sign of a histogram's buckets.
+// 4. Combining Clues:
+//
+// The most reliable approach is to combine these clues. For example:
+//
+// If a metric ends with _total and is used with rate(), it's almost certainly a counter.
+// If a metric has the le label and is used with histogram_quantile(), it's definitely a histogram.
+func inferMetricType(query string, metricName string, labels map[string]string) model.MetricType {
+	query = strings.ToLower(query)
+
+	if strings.HasSuffix(metricName, "_total") || strings.HasSuffix(metricName, "_count") {
+		if strings.Contains(query, "rate(") || strings.Contains(query, "irate(") || strings.Contains(query, "increase(") {
+			return model.MetricTypeSum
+		}
+		if strings.Contains(query, "sum_over_time(") || strings.Contains(query, "count_over_time(") {
+			return model.MetricTypeSum
+		}
+	}
+
+	// Check for common functions used with counters even without _total/_count
+	// we do this at the last
+	counterFunctionsRegexMap := map[string]string{
+		"rate":            `rate\(`,
+		"irate":           `irate\(`,
+		"increase":        `increase\(`,
+		"sum_over_time":   `sum_over_time\(`,
+		"count_over_time": `count_over_time\(`,
+	}
+	for fn := range counterFunctionsRegexMap {
+		if strings.Contains(query, fn) {
+			// Use a regex to check if function is used on the current metric
+			re := regexp.MustCompile(fn + `\s*\(\s*` + regexp.QuoteMeta(metricName) + `\b`)
+			if re.MatchString(query) {
+				return model.MetricTypeGauge
+			}
+		}
+	}
+
+	if strings.HasSuffix(metricName, "_bucket") || labels["le"] != "" {
+		if strings.Contains(query, "histogram_quantile(") {
+			return model.MetricTypeHistogram
+		}
+	}
+
+	if strings.HasSuffix(metricName, "_sum") {
+		// Could be summary or histogram, need more context if possible
+		if strings.Contains(query, "histogram_quantile(") {
+			return model.MetricTypeSummary
+		}
+		return model.MetricTypeSum
+	}
+
+	if strings.Contains(query, "deriv(") {
+		return model.MetricTypeGauge
+	}
+
+	// Default to gauge if no strong indicators
+	return model.MetricTypeGauge
+}
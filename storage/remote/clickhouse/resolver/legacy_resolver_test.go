@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+func TestInferMetricType(t *testing.T) {
+	tests := []struct {
+		query      string
+		metricName string
+		labels     map[string]string
+		expected   model.MetricType
+	}{
+		{
+			query:      "rate(metric_total[5m])",
+			metricName: "metric_total",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeSum,
+		},
+		{
+			query:      "histogram_quantile(0.95, metric_bucket)",
+			metricName: "metric_bucket",
+			labels:     map[string]string{"le": "0.95"},
+			expected:   model.MetricTypeHistogram,
+		},
+		{
+			query:      "sum_over_time(metric_sum[5m])",
+			metricName: "metric_sum",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		},
+		{
+			query:      "metric_sum[5m]",
+			metricName: "metric_sum",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeSum,
+		},
+		{
+			query:      "deriv(metric)",
+			metricName: "metric",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		},
+		{
+			query:      "rate(metric[5m])",
+			metricName: "metric",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		},
+		{
+			query:      "metric",
+			metricName: "metric",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		},
+		{
+			query:      "rate(chi_clickhouse_metric_DiskDataBytes[5m])",
+			metricName: "chi_clickhouse_metric_DiskDataBytes",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		}, {
+			query:      "sum(rate(node_network_receive_bytes_total{cluster=\"demo-acc-cluster\", job=\"integrations/node_exporter\"}[$__rate_interval])) by (instance)",
+			metricName: "chi_clickhouse_metric_DiskDataBytes",
+			labels:     map[string]string{},
+			expected:   model.MetricTypeGauge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			result := inferMetricType(test.query, test.metricName, test.labels)
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestLegacyHeuristicResolver_ResolveMetricType(t *testing.T) {
+	r := NewLegacyHeuristicResolver()
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "http_requests_total"),
+		labels.MustNewMatcher(labels.MatchEqual, "query_hint", "rate(http_requests_total[5m])"),
+	}
+
+	metricType, err := r.ResolveMetricType(context.Background(), matchers)
+	if err != nil {
+		t.Fatalf("ResolveMetricType() unexpected error: %v", err)
+	}
+	if metricType != model.MetricTypeSum {
+		t.Errorf("ResolveMetricType() = %v, want %v", metricType, model.MetricTypeSum)
+	}
+}
@@ -0,0 +1,63 @@
+// Package resolver provides model.MetricTypeResolver implementations used by
+// Reader.Read to decide which otel_metrics_* table a query's series live in.
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// ErrUnresolved is returned by a resolver that cannot determine a metric
+// type for the given matchers, signalling a Chain to try the next resolver.
+var ErrUnresolved = errors.New("resolver: metric type could not be resolved")
+
+// Chain tries each resolver in order and returns the first successful
+// result, allowing e.g. a ClickHouse-backed resolver to be layered in front
+// of a static fallback and, ultimately, a legacy heuristic.
+type Chain struct {
+	resolvers []model.MetricTypeResolver
+}
+
+// NewChain builds a Chain that consults resolvers in the given order.
+func NewChain(resolvers ...model.MetricTypeResolver) *Chain {
+	return &Chain{resolvers: resolvers}
+}
+
+// ResolveMetricType implements model.MetricTypeResolver.
+func (c *Chain) ResolveMetricType(ctx context.Context, matchers []*labels.Matcher) (model.MetricType, error) {
+	var lastErr error
+	for _, r := range c.resolvers {
+		metricType, err := r.ResolveMetricType(ctx, matchers)
+		if err == nil {
+			return metricType, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = ErrUnresolved
+	}
+	return "", lastErr
+}
+
+// metricName returns the value of the __name__ matcher, if any.
+func metricName(matchers []*labels.Matcher) string {
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// serviceName returns the value of the service_name matcher, if any.
+func serviceName(matchers []*labels.Matcher) string {
+	for _, m := range matchers {
+		if m.Name == "service_name" && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
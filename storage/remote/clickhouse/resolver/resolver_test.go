@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+func TestStaticResolver_ResolveMetricType(t *testing.T) {
+	r := NewStaticResolver(map[string]string{
+		"http_requests_total": "COUNTER",
+		"queue_depth":         "Gauge",
+	})
+
+	tests := []struct {
+		name      string
+		metric    string
+		want      model.MetricType
+		expectErr bool
+	}{
+		{name: "counter maps to sum", metric: "http_requests_total", want: model.MetricTypeSum},
+		{name: "case-insensitive gauge", metric: "queue_depth", want: model.MetricTypeGauge},
+		{name: "unknown metric errors", metric: "unknown_metric", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers := []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "__name__", tt.metric)}
+			got, err := r.ResolveMetricType(context.Background(), matchers)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ResolveMetricType() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && got != tt.want {
+				t.Errorf("ResolveMetricType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type stubResolver struct {
+	metricType model.MetricType
+	err        error
+}
+
+func (s stubResolver) ResolveMetricType(context.Context, []*labels.Matcher) (model.MetricType, error) {
+	return s.metricType, s.err
+}
+
+func TestChain_ResolveMetricType(t *testing.T) {
+	chain := NewChain(
+		stubResolver{err: ErrUnresolved},
+		stubResolver{metricType: model.MetricTypeHistogram},
+		stubResolver{metricType: model.MetricTypeGauge},
+	)
+
+	got, err := chain.ResolveMetricType(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ResolveMetricType() unexpected error: %v", err)
+	}
+	if got != model.MetricTypeHistogram {
+		t.Errorf("ResolveMetricType() = %v, want %v", got, model.MetricTypeHistogram)
+	}
+}
+
+func TestChain_ResolveMetricType_AllFail(t *testing.T) {
+	chain := NewChain(stubResolver{err: ErrUnresolved}, stubResolver{err: ErrUnresolved})
+
+	if _, err := chain.ResolveMetricType(context.Background(), nil); err == nil {
+		t.Error("ResolveMetricType() expected error when all resolvers fail")
+	}
+}
@@ -0,0 +1,52 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// openMetricsTypes maps OpenMetrics-style type names, as operators would
+// write them in a static metadata map, to the equivalent model.MetricType.
+var openMetricsTypes = map[string]model.MetricType{
+	"gauge":     model.MetricTypeGauge,
+	"counter":   model.MetricTypeSum,
+	"histogram": model.MetricTypeHistogram,
+	"summary":   model.MetricTypeSummary,
+}
+
+// StaticResolver resolves metric types from an operator-supplied map of
+// metric name to OpenMetrics type (COUNTER, GAUGE, HISTOGRAM, SUMMARY),
+// letting operators seed types statically when ClickHouse lookups aren't
+// desired or a metric hasn't been written yet.
+type StaticResolver struct {
+	types map[string]model.MetricType
+}
+
+// NewStaticResolver builds a StaticResolver from name -> OpenMetrics type
+// (case-insensitive). Unrecognized type strings are ignored.
+func NewStaticResolver(metadata map[string]string) *StaticResolver {
+	types := make(map[string]model.MetricType, len(metadata))
+	for name, t := range metadata {
+		if metricType, ok := openMetricsTypes[strings.ToLower(t)]; ok {
+			types[name] = metricType
+		}
+	}
+	return &StaticResolver{types: types}
+}
+
+// ResolveMetricType implements model.MetricTypeResolver.
+func (r *StaticResolver) ResolveMetricType(_ context.Context, matchers []*labels.Matcher) (model.MetricType, error) {
+	name := metricName(matchers)
+	if name == "" {
+		return "", fmt.Errorf("%w: no __name__ matcher in query", ErrUnresolved)
+	}
+	metricType, ok := r.types[name]
+	if !ok {
+		return "", fmt.Errorf("%w: no static metadata for metric %q", ErrUnresolved, name)
+	}
+	return metricType, nil
+}
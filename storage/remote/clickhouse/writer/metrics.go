@@ -0,0 +1,35 @@
+package writer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds the counters exposed for exemplar ingestion.
+type Metrics struct {
+	ExemplarsWritten prometheus.Counter
+	ExemplarsDropped prometheus.Counter
+}
+
+// NewMetrics registers and returns the writer's metrics with reg. reg may
+// be nil, in which case the metrics are created but left unregistered
+// (useful in tests).
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		ExemplarsWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "writer",
+			Name:      "exemplars_written_total",
+			Help:      "Number of exemplars persisted to otel_metrics_exemplars.",
+		}),
+		ExemplarsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "clickhouse",
+			Subsystem: "writer",
+			Name:      "exemplars_dropped_total",
+			Help:      "Number of exemplars dropped for exceeding the 128-byte label-set limit.",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(m.ExemplarsWritten, m.ExemplarsDropped)
+	}
+
+	return m
+}
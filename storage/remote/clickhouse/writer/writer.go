@@ -0,0 +1,417 @@
+// Package writer implements storage.WriteClient for the ClickHouse remote
+// backend, translating Prometheus remote-write samples into batched
+// INSERTs against the otel_metrics_* tables.
+package writer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/fingerprint"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/histogram"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/lru"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+)
+
+// exemplarsTable holds exemplars for every metric type, unlike tableMapping
+// which is keyed per otel_metrics_* table.
+const exemplarsTable = "otel_metrics_exemplars"
+
+// resourceLabelNames are the Prometheus label names pulled out of a
+// series into ResourceAttributes rather than Attributes, mirroring how the
+// OTel Collector's prometheusremotewrite exporter separates resource-level
+// attributes (job/instance, here as service.name/service.instance.id) from
+// per-point attributes.
+var resourceLabelNames = map[string]string{
+	"job":      "service.name",
+	"instance": "service.instance.id",
+}
+
+// tableMapping maps metric types to their corresponding tables, matching
+// query.Builder.
+var tableMapping = map[model.MetricType]string{
+	model.MetricTypeGauge:           "otel_metrics_gauge",
+	model.MetricTypeSum:             "otel_metrics_sum",
+	model.MetricTypeHistogram:       "otel_metrics_histogram",
+	model.MetricTypeSummary:         "otel_metrics_summary",
+	model.MetricTypeNativeHistogram: "otel_metrics_exponential_histogram",
+}
+
+// startTimeCacheSize and startTimeCacheTTL bound the per-series cumulative
+// start-time tracker so a long-running Writer doesn't grow it unbounded; a
+// series absent for longer than the TTL is treated as new (and therefore
+// reset) the next time it's seen, same trade-off adjuster.Adjuster makes on
+// the read side.
+const (
+	startTimeCacheSize = 16384
+	startTimeCacheTTL  = time.Hour
+)
+
+// cumulativeState tracks what Writer needs to derive a StartTimeUnix for a
+// cumulative series across Write calls: the timestamp its current value
+// sequence began at, and the last value seen, so a drop can be recognized
+// as a counter reset.
+type cumulativeState struct {
+	startTime time.Time
+	lastValue float64
+}
+
+// Writer implements storage.WriteClient for ClickHouse.
+type Writer struct {
+	client     *client.Client
+	resolver   model.MetricTypeResolver
+	metrics    *Metrics
+	startTimes *lru.Cache[string, *cumulativeState]
+}
+
+// NewWriter creates a Writer that inserts into c using resolver to decide,
+// per series, which otel_metrics_* table it belongs in.
+//
+// resolver must be able to resolve a metric it has never seen before: a
+// bare *resolver.ClickHouseResolver cannot, since it works by finding an
+// existing row for the metric, and a brand-new metric's first write has no
+// row yet. Such a resolver returns resolver.ErrUnresolved, and Write fails
+// outright. Pair it in a resolver.Chain with a fallback that does not need
+// an existing row: resolver.NewLegacyHeuristicResolver as the last entry
+// never errors, while a resolver.NewStaticResolver resolves only the
+// metrics seeded in its map and returns resolver.ErrUnresolved for others.
+func NewWriter(c *client.Client, resolver model.MetricTypeResolver) *Writer {
+	return NewWriterWithMetrics(c, resolver, NewMetrics(nil))
+}
+
+// NewWriterWithMetrics is like NewWriter but lets callers supply Metrics
+// registered against their own prometheus.Registerer instead of the
+// unregistered defaults NewWriter uses.
+func NewWriterWithMetrics(c *client.Client, resolver model.MetricTypeResolver, metrics *Metrics) *Writer {
+	return &Writer{
+		client:     c,
+		resolver:   resolver,
+		metrics:    metrics,
+		startTimes: lru.New[string, *cumulativeState](startTimeCacheSize, startTimeCacheTTL),
+	}
+}
+
+// Write implements storage.WriteClient. It groups the request's series by
+// resolved metric type and flushes one batch INSERT per otel_metrics_*
+// table touched.
+func (w *Writer) Write(ctx context.Context, req *prompb.WriteRequest) error {
+	batches := make(map[model.MetricType]client.Batch, len(tableMapping))
+	var exemplarsBatch client.Batch
+
+	for _, ts := range req.Timeseries {
+		metricType, metricName, serviceName, attrs, resourceAttrs, err := classify(ts.Labels)
+		if err != nil {
+			return err
+		}
+		if metricType == "" {
+			switch {
+			case len(ts.Histograms) > 0:
+				metricType = model.MetricTypeNativeHistogram
+			default:
+				matchers := equalityMatchers(ts.Labels)
+				metricType, err = w.resolver.ResolveMetricType(ctx, matchers)
+				if err != nil {
+					return fmt.Errorf("writer: resolving metric type for %q: %w", metricName, err)
+				}
+			}
+		}
+
+		batch, ok := batches[metricType]
+		if !ok {
+			batch, err = w.newBatch(ctx, metricType)
+			if err != nil {
+				return err
+			}
+			batches[metricType] = batch
+		}
+
+		if metricType == model.MetricTypeNativeHistogram {
+			if err := appendHistograms(batch, metricName, serviceName, attrs, resourceAttrs, ts.Histograms); err != nil {
+				return err
+			}
+		} else if err := w.appendSamples(batch, metricType, metricName, serviceName, attrs, resourceAttrs, ts.Samples); err != nil {
+			return err
+		}
+
+		if len(ts.Exemplars) > 0 {
+			if exemplarsBatch == nil {
+				exemplarsBatch, err = w.newExemplarBatch(ctx)
+				if err != nil {
+					return err
+				}
+			}
+			if err := w.appendExemplars(exemplarsBatch, metricName, serviceName, attrs, resourceAttrs, ts.Exemplars); err != nil {
+				return err
+			}
+		}
+	}
+
+	for metricType, batch := range batches {
+		if err := batch.Send(); err != nil {
+			return fmt.Errorf("writer: flushing batch for %s: %w", tableMapping[metricType], err)
+		}
+	}
+	if exemplarsBatch != nil {
+		if err := exemplarsBatch.Send(); err != nil {
+			return fmt.Errorf("writer: flushing batch for %s: %w", exemplarsTable, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) newBatch(ctx context.Context, metricType model.MetricType) (client.Batch, error) {
+	table, ok := tableMapping[metricType]
+	if !ok {
+		return nil, fmt.Errorf("writer: unsupported metric type: %s", metricType)
+	}
+	var columns string
+	switch metricType {
+	case model.MetricTypeGauge:
+		columns = "TimeUnix, Value, MetricName, ServiceName, Attributes, ResourceAttributes"
+	case model.MetricTypeNativeHistogram:
+		columns = "TimeUnix, Count, Sum, Scale, ZeroCount, ZeroThreshold, " +
+			"PositiveOffset, PositiveBucketCounts, NegativeOffset, NegativeBucketCounts, " +
+			"MetricName, ServiceName, Attributes, ResourceAttributes"
+	case model.MetricTypeHistogram, model.MetricTypeSummary:
+		// Cumulative like Sum below, but these tables have no Value column:
+		// a sample's value goes in Sum, the column query.Builder reads back.
+		columns = "TimeUnix, Sum, StartTimeUnix, MetricName, ServiceName, Attributes, ResourceAttributes"
+	default:
+		// Sum (like Histogram and Summary above) is a cumulative type, so
+		// StartTimeUnix is carried too (see appendSamples), giving the
+		// reader's counter adjuster a real reset point to key off instead of
+		// ClickHouse's zero-value default.
+		columns = "TimeUnix, Value, StartTimeUnix, MetricName, ServiceName, Attributes, ResourceAttributes"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s)", table, columns)
+	batch, err := w.client.PrepareBatch(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("writer: preparing batch for %s: %w", table, err)
+	}
+	return batch, nil
+}
+
+// newExemplarBatch prepares a batch insert against otel_metrics_exemplars,
+// separate from newBatch's per-metric-type tables since exemplars aren't
+// keyed by model.MetricType.
+func (w *Writer) newExemplarBatch(ctx context.Context) (client.Batch, error) {
+	query := fmt.Sprintf("INSERT INTO %s (%s)", exemplarsTable,
+		"TimeUnix, Value, MetricName, ServiceName, Attributes, ResourceAttributes, FilteredAttributes")
+	batch, err := w.client.PrepareBatch(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("writer: preparing batch for %s: %w", exemplarsTable, err)
+	}
+	return batch, nil
+}
+
+// appendExemplars writes one otel_metrics_exemplars row per prompb.Exemplar
+// attached to a series, dropping (and counting) any whose label set exceeds
+// Prometheus's 128-byte exemplar limit rather than truncating it.
+func (w *Writer) appendExemplars(batch client.Batch, metricName, serviceName string, attrs, resourceAttrs map[string]string, exemplars []prompb.Exemplar) error {
+	for _, e := range exemplars {
+		if exemplarLabelSetSize(e.Labels) > exemplar.ExemplarMaxLabelSetLength {
+			w.metrics.ExemplarsDropped.Inc()
+			continue
+		}
+
+		if err := batch.Append(
+			time.UnixMilli(e.Timestamp),
+			e.Value,
+			metricName,
+			serviceName,
+			attrs,
+			resourceAttrs,
+			exemplarLabelsMap(e.Labels),
+		); err != nil {
+			return fmt.Errorf("writer: appending exemplar for %s: %w", metricName, err)
+		}
+		w.metrics.ExemplarsWritten.Inc()
+	}
+	return nil
+}
+
+// exemplarLabelsMap converts a prompb.Exemplar's labels into the map form
+// FilteredAttributes is stored as.
+func exemplarLabelsMap(lbls []prompb.Label) map[string]string {
+	m := make(map[string]string, len(lbls))
+	for _, l := range lbls {
+		m[l.Name] = l.Value
+	}
+	return m
+}
+
+// exemplarLabelSetSize sums the byte length of each label's name and value,
+// matching how Prometheus enforces exemplar.ExemplarMaxLabelSetLength.
+func exemplarLabelSetSize(lbls []prompb.Label) int {
+	size := 0
+	for _, l := range lbls {
+		size += len(l.Name) + len(l.Value)
+	}
+	return size
+}
+
+// appendSamples writes one row per prompb.Sample. For cumulative metric
+// types (everything but Gauge) it also derives a StartTimeUnix from
+// startTimeFor, tracked across Write calls: a real series-start timestamp,
+// rather than leaving the column at ClickHouse's zero-value default, so the
+// reader's counter adjuster has something meaningful to detect resets and
+// stale series from. Prometheus's remote-write wire format carries no
+// created-timestamp of its own, so this is necessarily a heuristic, scoped
+// to what this Writer instance has observed: a reset is inferred whenever a
+// series' value drops below the last one seen.
+func (w *Writer) appendSamples(batch client.Batch, metricType model.MetricType, metricName, serviceName string, attrs, resourceAttrs map[string]string, samples []prompb.Sample) error {
+	var key string
+	if metricType != model.MetricTypeGauge {
+		key = seriesFingerprint(metricName, serviceName, attrs, resourceAttrs)
+	}
+	for _, s := range samples {
+		args := []interface{}{time.UnixMilli(s.Timestamp), s.Value}
+		if metricType != model.MetricTypeGauge {
+			args = append(args, w.startTimeFor(key, time.UnixMilli(s.Timestamp), s.Value))
+		}
+		args = append(args, metricName, serviceName, attrs, resourceAttrs)
+		if err := batch.Append(args...); err != nil {
+			return fmt.Errorf("writer: appending sample for %s: %w", metricName, err)
+		}
+	}
+	return nil
+}
+
+// startTimeFor returns the start time of the cumulative period key's series
+// is currently in: the first timestamp observed for it, or the timestamp a
+// later value drop (a counter reset) was observed at, whichever is more
+// recent. It updates the tracked state for the next call regardless.
+func (w *Writer) startTimeFor(key string, timestamp time.Time, value float64) time.Time {
+	state, seen := w.startTimes.Get(key)
+	if !seen {
+		state = &cumulativeState{startTime: timestamp}
+	} else if value < state.lastValue {
+		state.startTime = timestamp
+	}
+	state.lastValue = value
+	w.startTimes.Set(key, state)
+	return state.startTime
+}
+
+// seriesFingerprint canonicalizes a series' identifying columns into a
+// single cache key for startTimes, regardless of the order ClickHouse (or a
+// map literal) would return attrs/resourceAttrs entries in.
+func seriesFingerprint(metricName, serviceName string, attrs, resourceAttrs map[string]string) string {
+	return metricName + "|" + serviceName + "|" + fingerprint.Of(attrs) + "|" + fingerprint.Of(resourceAttrs)
+}
+
+// appendHistograms writes one otel_metrics_exponential_histogram row per
+// prompb.Histogram, expanding its span/delta-encoded buckets into the dense
+// offset+counts form the table stores, via the histogram package shared
+// with the reader.
+func appendHistograms(batch client.Batch, metricName, serviceName string, attrs, resourceAttrs map[string]string, histograms []prompb.Histogram) error {
+	for _, h := range histograms {
+		positive := expandBuckets(h.PositiveSpans, h.PositiveDeltas, h.PositiveCounts)
+		negative := expandBuckets(h.NegativeSpans, h.NegativeDeltas, h.NegativeCounts)
+
+		if err := batch.Append(
+			time.UnixMilli(h.Timestamp),
+			histogramCount(h),
+			h.Sum,
+			h.Schema,
+			histogramZeroCount(h),
+			h.ZeroThreshold,
+			positive.Offset,
+			positive.Counts,
+			negative.Offset,
+			negative.Counts,
+			metricName,
+			serviceName,
+			attrs,
+			resourceAttrs,
+		); err != nil {
+			return fmt.Errorf("writer: appending histogram for %s: %w", metricName, err)
+		}
+	}
+	return nil
+}
+
+// expandBuckets expands a side (positive or negative) of a native
+// histogram's spans into dense Buckets, using deltas for an integer
+// histogram or counts for a float one, whichever prompb populated.
+func expandBuckets(spans []prompb.BucketSpan, deltas []int64, counts []float64) histogram.Buckets {
+	if len(counts) > 0 {
+		return histogram.ExpandCounts(spans, counts)
+	}
+	return histogram.ExpandDeltas(spans, deltas)
+}
+
+// histogramCount and histogramZeroCount unwrap prompb.Histogram's Count and
+// ZeroCount oneofs, which carry either an integer or float value depending
+// on whether the source histogram is integer- or float-backed.
+func histogramCount(h prompb.Histogram) float64 {
+	switch c := h.Count.(type) {
+	case *prompb.Histogram_CountFloat:
+		return c.CountFloat
+	case *prompb.Histogram_CountInt:
+		return float64(c.CountInt)
+	default:
+		return 0
+	}
+}
+
+func histogramZeroCount(h prompb.Histogram) float64 {
+	switch c := h.ZeroCount.(type) {
+	case *prompb.Histogram_ZeroCountFloat:
+		return c.ZeroCountFloat
+	case *prompb.Histogram_ZeroCountInt:
+		return float64(c.ZeroCountInt)
+	default:
+		return 0
+	}
+}
+
+// classify splits lbls into metric name, service name, attributes, and
+// resource attributes, OTLP-style. It returns an empty metricType, leaving
+// it to the caller's MetricTypeResolver, since label sets alone don't
+// identify the OTel metric type.
+func classify(lbls []prompb.Label) (metricType model.MetricType, metricName, serviceName string, attrs, resourceAttrs map[string]string, err error) {
+	attrs = make(map[string]string, len(lbls))
+	resourceAttrs = make(map[string]string)
+
+	for _, l := range lbls {
+		switch l.Name {
+		case labels.MetricName:
+			metricName = l.Value
+		case "job":
+			serviceName = l.Value
+			resourceAttrs[resourceLabelNames["job"]] = l.Value
+		case "instance":
+			resourceAttrs[resourceLabelNames["instance"]] = l.Value
+		default:
+			attrs[l.Name] = l.Value
+		}
+	}
+
+	if metricName == "" {
+		return "", "", "", nil, nil, fmt.Errorf("writer: series missing __name__ label")
+	}
+
+	return "", metricName, serviceName, attrs, resourceAttrs, nil
+}
+
+// equalityMatchers converts a series' labels into MatchEqual matchers so
+// they can be passed to a model.MetricTypeResolver built for querying.
+func equalityMatchers(lbls []prompb.Label) []*labels.Matcher {
+	matchers := make([]*labels.Matcher, 0, len(lbls))
+	for _, l := range lbls {
+		matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, l.Name, l.Value))
+	}
+	return matchers
+}
+
+// Type implements storage.WriteClient.
+func (w *Writer) Type() string {
+	return "clickhouse"
+}
@@ -0,0 +1,388 @@
+package writer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	chclient "github.com/prometheus/prometheus/storage/remote/clickhouse/client"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/internal/histogram"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/model"
+	"github.com/prometheus/prometheus/storage/remote/clickhouse/resolver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubResolver always returns metricType, ignoring the matchers.
+type stubResolver struct {
+	metricType model.MetricType
+}
+
+func (s stubResolver) ResolveMetricType(context.Context, []*labels.Matcher) (model.MetricType, error) {
+	return s.metricType, nil
+}
+
+type mockBatch struct {
+	mock.Mock
+	driver.Batch
+}
+
+func (m *mockBatch) Append(v ...interface{}) error {
+	return m.Called(v).Error(0)
+}
+
+func (m *mockBatch) Send() error {
+	return m.Called().Error(0)
+}
+
+type mockConn struct {
+	mock.Mock
+	driver.Conn
+}
+
+func (m *mockConn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	ret := m.Called(ctx, query)
+	return ret.Get(0).(driver.Batch), ret.Error(1)
+}
+
+func TestWriter_Write(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.AnythingOfType("string")).Return(batch, nil)
+	batch.On("Append", mock.Anything).Return(nil)
+	batch.On("Send").Return(nil)
+
+	w := NewWriter(c, stubResolver{metricType: model.MetricTypeGauge})
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "queue_depth"},
+					{Name: "job", Value: "api"},
+					{Name: "env", Value: "prod"},
+				},
+				Samples: []prompb.Sample{
+					{Timestamp: 1700000000000, Value: 42},
+				},
+			},
+		},
+	}
+
+	err := w.Write(context.Background(), req)
+	assert.NoError(t, err)
+
+	conn.AssertExpectations(t)
+	batch.AssertExpectations(t)
+}
+
+func TestWriter_Write_NativeHistogram(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.AnythingOfType("string")).Return(batch, nil)
+	batch.On("Append", mock.Anything).Return(nil)
+	batch.On("Send").Return(nil)
+
+	w := NewWriter(c, stubResolver{metricType: model.MetricTypeGauge})
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "request_latency_seconds"},
+					{Name: "job", Value: "api"},
+				},
+				Histograms: []prompb.Histogram{
+					{
+						Count:          &prompb.Histogram_CountFloat{CountFloat: 4},
+						Sum:            12.5,
+						Schema:         3,
+						ZeroThreshold:  0.001,
+						ZeroCount:      &prompb.Histogram_ZeroCountFloat{ZeroCountFloat: 0},
+						PositiveSpans:  []prompb.BucketSpan{{Offset: 0, Length: 2}},
+						PositiveCounts: []float64{1, 3},
+						Timestamp:      1700000000000,
+					},
+				},
+			},
+		},
+	}
+
+	err := w.Write(context.Background(), req)
+	assert.NoError(t, err)
+
+	conn.AssertExpectations(t)
+	batch.AssertExpectations(t)
+}
+
+// TestWriter_Write_NativeHistogram_RoundTripsSchemaAndBothSides exercises
+// appendHistograms with a schema=2 histogram that has a non-zero zero
+// bucket and populated spans on both sides, through the mocked
+// driver.Conn/Batch, then runs the appended dense offset+counts back
+// through histogram.CollapseCounts (the same re-encoding
+// convertToHistogramReadResponse uses on read) to confirm the original
+// spans/counts survive the round trip on both sides, not just positive.
+func TestWriter_Write_NativeHistogram_RoundTripsSchemaAndBothSides(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.AnythingOfType("string")).Return(batch, nil)
+	batch.On("Send").Return(nil)
+
+	var appended []interface{}
+	batch.On("Append", mock.Anything).Run(func(args mock.Arguments) {
+		appended = args.Get(0).([]interface{})
+	}).Return(nil)
+
+	w := NewWriter(c, stubResolver{metricType: model.MetricTypeGauge})
+
+	wantPositiveSpans := []prompb.BucketSpan{{Offset: 0, Length: 2}}
+	wantPositiveCounts := []float64{1, 3}
+	wantNegativeSpans := []prompb.BucketSpan{{Offset: 1, Length: 2}}
+	wantNegativeCounts := []float64{2, 5}
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "request_latency_seconds"},
+					{Name: "job", Value: "api"},
+				},
+				Histograms: []prompb.Histogram{
+					{
+						Count:          &prompb.Histogram_CountFloat{CountFloat: 11},
+						Sum:            12.5,
+						Schema:         2,
+						ZeroThreshold:  0.001,
+						ZeroCount:      &prompb.Histogram_ZeroCountFloat{ZeroCountFloat: 7},
+						PositiveSpans:  wantPositiveSpans,
+						PositiveCounts: wantPositiveCounts,
+						NegativeSpans:  wantNegativeSpans,
+						NegativeCounts: wantNegativeCounts,
+						Timestamp:      1700000000000,
+					},
+				},
+			},
+		},
+	}
+
+	err := w.Write(context.Background(), req)
+	assert.NoError(t, err)
+
+	// appended columns: TimeUnix, Count, Sum, Scale, ZeroCount, ZeroThreshold,
+	// PositiveOffset, PositiveBucketCounts, NegativeOffset, NegativeBucketCounts, ...
+	assert.Equal(t, float64(11), appended[1])
+	assert.Equal(t, 12.5, appended[2])
+	assert.Equal(t, int32(2), appended[3])
+	assert.Equal(t, float64(7), appended[4])
+	assert.Equal(t, 0.001, appended[5])
+
+	positiveDense := histogram.Buckets{Offset: appended[6].(int32), Counts: appended[7].([]float64)}
+	gotPositiveSpans, gotPositiveCounts := histogram.CollapseCounts(positiveDense)
+	assert.Equal(t, wantPositiveSpans, gotPositiveSpans)
+	assert.Equal(t, wantPositiveCounts, gotPositiveCounts)
+
+	negativeDense := histogram.Buckets{Offset: appended[8].(int32), Counts: appended[9].([]float64)}
+	gotNegativeSpans, gotNegativeCounts := histogram.CollapseCounts(negativeDense)
+	assert.Equal(t, wantNegativeSpans, gotNegativeSpans)
+	assert.Equal(t, wantNegativeCounts, gotNegativeCounts)
+
+	conn.AssertExpectations(t)
+	batch.AssertExpectations(t)
+}
+
+func TestWriter_Write_Exemplars(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	exemplarsBatch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.MatchedBy(func(q string) bool {
+		return !strings.Contains(q, "otel_metrics_exemplars")
+	})).Return(batch, nil)
+	conn.On("PrepareBatch", mock.Anything, mock.MatchedBy(func(q string) bool {
+		return strings.Contains(q, "otel_metrics_exemplars")
+	})).Return(exemplarsBatch, nil)
+	batch.On("Append", mock.Anything).Return(nil)
+	batch.On("Send").Return(nil)
+	exemplarsBatch.On("Append", mock.Anything).Return(nil)
+	exemplarsBatch.On("Send").Return(nil)
+
+	w := NewWriter(c, stubResolver{metricType: model.MetricTypeSum})
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "http_request_duration_seconds"},
+					{Name: "job", Value: "api"},
+				},
+				Samples: []prompb.Sample{
+					{Timestamp: 1700000000000, Value: 0.42},
+				},
+				Exemplars: []prompb.Exemplar{
+					{
+						Labels:    []prompb.Label{{Name: "trace_id", Value: "abc123"}},
+						Value:     0.42,
+						Timestamp: 1700000000000,
+					},
+				},
+			},
+		},
+	}
+
+	err := w.Write(context.Background(), req)
+	assert.NoError(t, err)
+
+	conn.AssertExpectations(t)
+	batch.AssertExpectations(t)
+	exemplarsBatch.AssertExpectations(t)
+}
+
+func TestWriter_Write_TracksStartTimeAcrossCalls(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.AnythingOfType("string")).Return(batch, nil)
+	batch.On("Send").Return(nil)
+
+	var appended [][]interface{}
+	batch.On("Append", mock.Anything).Run(func(args mock.Arguments) {
+		appended = append(appended, args.Get(0).([]interface{}))
+	}).Return(nil)
+
+	w := NewWriter(c, stubResolver{metricType: model.MetricTypeSum})
+
+	write := func(timestamp int64, value float64) {
+		req := &prompb.WriteRequest{
+			Timeseries: []prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{
+						{Name: "__name__", Value: "http_requests_total"},
+						{Name: "job", Value: "api"},
+					},
+					Samples: []prompb.Sample{{Timestamp: timestamp, Value: value}},
+				},
+			},
+		}
+		assert.NoError(t, w.Write(context.Background(), req))
+	}
+
+	write(1700000000000, 10)
+	write(1700000010000, 20)
+	write(1700000020000, 5) // value dropped: counter reset
+
+	if len(appended) != 3 {
+		t.Fatalf("appended %d rows, want 3", len(appended))
+	}
+	// columns are TimeUnix, Value, StartTimeUnix, ...
+	firstStart := appended[0][2]
+	if appended[1][2] != firstStart {
+		t.Errorf("StartTimeUnix changed without a reset: got %v, want %v", appended[1][2], firstStart)
+	}
+	if appended[2][2] == firstStart {
+		t.Errorf("StartTimeUnix did not advance after a value drop (counter reset)")
+	}
+	if appended[2][2] != time.UnixMilli(1700000020000) {
+		t.Errorf("StartTimeUnix after reset = %v, want the reset sample's own timestamp", appended[2][2])
+	}
+}
+
+// unresolvedResolver simulates a bare *resolver.ClickHouseResolver seeing a
+// brand-new metric: there's no row for it yet, so it always fails with
+// resolver.ErrUnresolved.
+type unresolvedResolver struct{}
+
+func (unresolvedResolver) ResolveMetricType(context.Context, []*labels.Matcher) (model.MetricType, error) {
+	return "", resolver.ErrUnresolved
+}
+
+// TestWriter_Write_BareClickHouseResolverFailsFirstWrite documents, via a
+// failing case, why NewWriter's doc comment warns against passing a bare
+// *resolver.ClickHouseResolver: since it resolves by finding an existing
+// row, a metric's very first write has nothing to find and Write fails
+// outright instead of ever landing that first row.
+func TestWriter_Write_BareClickHouseResolverFailsFirstWrite(t *testing.T) {
+	conn := new(mockConn)
+	c := chclient.NewClientFromConn(conn, nil)
+	w := NewWriter(c, unresolvedResolver{})
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "brand_new_metric"},
+				},
+				Samples: []prompb.Sample{{Timestamp: 1700000000000, Value: 1}},
+			},
+		},
+	}
+
+	err := w.Write(context.Background(), req)
+	assert.ErrorIs(t, err, resolver.ErrUnresolved)
+	conn.AssertNotCalled(t, "PrepareBatch", mock.Anything, mock.Anything)
+}
+
+// TestWriter_Write_ChainWithFallbackResolvesFirstWrite shows the fix: pairing
+// the never-succeeding resolver above with a resolver.StaticResolver that
+// knows about the metric lets the first write through, as NewWriter's doc
+// comment recommends.
+func TestWriter_Write_ChainWithFallbackResolvesFirstWrite(t *testing.T) {
+	conn := new(mockConn)
+	batch := new(mockBatch)
+	c := chclient.NewClientFromConn(conn, nil)
+
+	conn.On("PrepareBatch", mock.Anything, mock.AnythingOfType("string")).Return(batch, nil)
+	batch.On("Append", mock.Anything).Return(nil)
+	batch.On("Send").Return(nil)
+
+	chain := resolver.NewChain(unresolvedResolver{}, resolver.NewStaticResolver(map[string]string{
+		"brand_new_metric": "gauge",
+	}))
+	w := NewWriter(c, chain)
+
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			{
+				Labels: []prompb.Label{
+					{Name: "__name__", Value: "brand_new_metric"},
+				},
+				Samples: []prompb.Sample{{Timestamp: 1700000000000, Value: 1}},
+			},
+		},
+	}
+
+	assert.NoError(t, w.Write(context.Background(), req))
+	conn.AssertExpectations(t)
+	batch.AssertExpectations(t)
+}
+
+func TestClassify(t *testing.T) {
+	_, metricName, serviceName, attrs, resourceAttrs, err := classify([]prompb.Label{
+		{Name: "__name__", Value: "http_requests_total"},
+		{Name: "job", Value: "api"},
+		{Name: "instance", Value: "api-1:9090"},
+		{Name: "env", Value: "prod"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http_requests_total", metricName)
+	assert.Equal(t, "api", serviceName)
+	assert.Equal(t, map[string]string{"env": "prod"}, attrs)
+	assert.Equal(t, map[string]string{"service.name": "api", "service.instance.id": "api-1:9090"}, resourceAttrs)
+}
+
+func TestClassify_MissingMetricName(t *testing.T) {
+	_, _, _, _, _, err := classify([]prompb.Label{{Name: "job", Value: "api"}})
+	assert.Error(t, err)
+}